@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/marianogappa/hts/marketcatalog"
+	"github.com/marianogappa/hts/signalrunner"
+	"github.com/marianogappa/hts/signaltranspiler"
+)
+
+const (
+	exitOK = iota
+	exitSignalErrors
+	exitUsage
+)
+
+const usage = `Usage: hts [command] [flags] [FILE...]
+
+Commands:
+  serve       start the web UI on $PORT (default when no command is given), checking markets against the
+              $MARKET_CATALOG snapshot if it's set
+  transpile   print the transpiled signals as JSON
+  run         transpile and check the signals, printing the results as JSON
+  fmt         print the signals as normalized signal text (-w rewrites the files)
+  catalog     refresh a market catalog snapshot with the exchanges in the given snapshot FILEs
+
+Signals are read from the given files, or from stdin if there are none or FILE is "-".
+Exit status is 1 if any signal has errors.
+`
+
+// runCLI runs a subcommand and returns the process exit status.
+func runCLI(args []string) int {
+	commands := map[string]func([]string) int{
+		"transpile": transpileCommand,
+		"run":       runCommand,
+		"fmt":       fmtCommand,
+		"catalog":   catalogCommand,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		return exitUsage
+	}
+	return command(args[1:])
+}
+
+func transpileCommand(args []string) int {
+	fs := newFlagSet("transpile")
+	newSignalTranspiler := transpilerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	st, err := newSignalTranspiler()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	outputs, status := transpileFiles(st, fs.Args())
+	if status != exitOK && outputs == nil {
+		return status
+	}
+	if err := printJSON(outputs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	return status
+}
+
+func runCommand(args []string) int {
+	fs := newFlagSet("run")
+	concurrency := fs.Int("concurrency", signalrunner.DefaultConcurrency, "maximum number of signals to check at the same time")
+	newSignalTranspiler := transpilerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	st, err := newSignalTranspiler()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	outputs, status := transpileFiles(st, fs.Args())
+	if status != exitOK && outputs == nil {
+		return status
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	result := signalrunner.NewSignalRunner(*concurrency).Run(ctx, outputs)
+	if err := printJSON(result); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if result.Summary.CheckErrors > 0 || result.Summary.Cancelled > 0 {
+		return exitSignalErrors
+	}
+	return status
+}
+
+func fmtCommand(args []string) int {
+	fs := newFlagSet("fmt")
+	inferred := fs.Bool("inferred", false, "include inferred instructions")
+	write := fs.Bool("w", false, "write the result back to the source files instead of stdout")
+	newSignalTranspiler := transpilerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	st, err := newSignalTranspiler()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	status := exitOK
+	for _, path := range paths {
+		input, err := readInput(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		formatted, err := st.Format(input, *inferred)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", path, err)
+			status = exitSignalErrors
+			continue
+		}
+		if *write && path != "-" {
+			if err := os.WriteFile(path, []byte(formatted+"\n"), 0644); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitUsage
+			}
+			continue
+		}
+		fmt.Println(formatted)
+	}
+	return status
+}
+
+// defaultCatalogPath is the snapshot that the catalog command refreshes by default: the one embedded in the binary,
+// relative to the repository root, so that rebuilding picks the refreshed markets up.
+const defaultCatalogPath = "marketcatalog/markets.json"
+
+// catalogCommand refreshes a market catalog snapshot: every exchange in the given snapshots replaces the one in it. A
+// snapshot that doesn't exist yet starts as the embedded catalog.
+func catalogCommand(args []string) int {
+	fs := newFlagSet("catalog")
+	output := fs.String("o", defaultCatalogPath, "snapshot to refresh")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	catalog, err := marketcatalog.LoadFile(*output)
+	if os.IsNotExist(err) {
+		catalog, err = marketcatalog.Default(), nil
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	for _, path := range fs.Args() {
+		source, err := marketcatalog.LoadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		catalog.Merge(source)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	defer f.Close()
+	if err := catalog.Write(f); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	fmt.Fprintf(os.Stderr, "%v: %v\n", *output, strings.Join(catalog.ExchangeNames(), ", "))
+	return exitOK
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: hts %v [flags] [FILE...]\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// transpilerFlags adds the flags that override the transpiler's defaults, and returns a function that builds the
+// transpiler once the flags are parsed.
+func transpilerFlags(fs *flag.FlagSet) func() (*signaltranspiler.SignalTranspiler, error) {
+	var (
+		exchange   = fs.String("exchange", "", "exchange to infer when a signal doesn't specify one (default \""+signaltranspiler.DefaultExchange+"\")")
+		timeout    = fs.Duration("timeout", 0, "timeout to infer when a signal doesn't specify one (default "+signaltranspiler.DefaultTimeout.String()+")")
+		maxTimeout = fs.Duration("max-timeout", 0, "longest timeout a signal may have (default "+signaltranspiler.DefaultMaxTimeout.String()+")")
+		exchanges  = fs.String("exchanges", "", "comma-separated exchanges that EXCHANGE accepts")
+		strict     = fs.Bool("strict", false, "infer nothing: every instruction must be in the signal, and its market must be listed")
+		dialect    = fs.String("dialect", "", "read signals in a dialect: "+strings.Join(signaltranspiler.DialectNames(), ", "))
+		extract    = fs.Bool("extract", false, "read signals as free-form text instead of one instruction per line")
+		catalog    = fs.String("catalog", "", "market catalog snapshot to check markets against (default the embedded one)")
+	)
+	return func() (*signaltranspiler.SignalTranspiler, error) {
+		if *timeout > 0 && *timeout < time.Minute {
+			return nil, fmt.Errorf("-timeout of %v is too short, timeouts are in whole minutes", *timeout)
+		}
+		o := transpilerOptions{
+			DefaultExchange:       *exchange,
+			DefaultTimeoutSeconds: int(timeout.Seconds()),
+			MaxTimeoutSeconds:     int(maxTimeout.Seconds()),
+			Strict:                *strict,
+			Dialect:               *dialect,
+			Extract:               *extract,
+			MarketCatalog:         *catalog,
+		}
+		if *exchanges != "" {
+			o.SupportedExchanges = strings.Split(*exchanges, ",")
+		}
+		return o.newSignalTranspiler()
+	}
+}
+
+// transpileFiles transpiles every signal in the given files (or stdin). It returns nil outputs if a file can't be
+// read, and exitSignalErrors if any signal has errors.
+func transpileFiles(st *signaltranspiler.SignalTranspiler, paths []string) ([]signaltranspiler.SignalTranspilerOutput, int) {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	var (
+		outputs = []signaltranspiler.SignalTranspilerOutput{}
+		status  = exitOK
+	)
+	for _, path := range paths {
+		input, err := readInput(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return nil, exitUsage
+		}
+		fileOutputs, err := st.TranspileAll(input)
+		if err != nil {
+			status = exitSignalErrors
+		}
+		outputs = append(outputs, fileOutputs...)
+	}
+	return outputs, status
+}
+
+func readInput(path string) (string, error) {
+	var (
+		bs  []byte
+		err error
+	)
+	if path == "-" {
+		bs, err = io.ReadAll(os.Stdin)
+	} else {
+		bs, err = os.ReadFile(path)
+	}
+	return strings.TrimRight(string(bs), "\n"), err
+}
+
+func printJSON(v interface{}) error {
+	bs, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(bs))
+	return nil
+}
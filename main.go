@@ -8,20 +8,39 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/marianogappa/hts/marketcatalog"
+	"github.com/marianogappa/hts/signalrunner"
 	"github.com/marianogappa/hts/signaltranspiler"
-	"github.com/marianogappa/signal-checker/signalchecker"
+	"github.com/marianogappa/signal-checker/common"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		log.Fatal("$PORT must be set")
 	}
+	if path := os.Getenv("MARKET_CATALOG"); path != "" {
+		catalog, err := marketcatalog.LoadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		marketCatalog = catalog
+	}
 
 	http.HandleFunc("/", rootHandler)
 	http.HandleFunc("/transpile", transpileHandler)
 	http.HandleFunc("/run", runHandler)
+	http.HandleFunc("/batch", batchHandler)
+	http.HandleFunc("/report", reportHandler)
+	http.HandleFunc("/format", formatHandler)
+	http.HandleFunc("/decompile", decompileHandler)
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))))
 
 	if err := http.ListenAndServe(fmt.Sprintf(":%v", port), nil); err != nil {
@@ -31,50 +50,232 @@ func main() {
 
 func transpileHandler(w http.ResponseWriter, r *http.Request) {
 	type body struct {
-		Input string `json:"input"`
+		Input   string            `json:"input"`
+		Options transpilerOptions `json:"options"`
 	}
 	decoder := json.NewDecoder(r.Body)
 	var b body
-	err := decoder.Decode(&b)
-	if err != nil {
-		log.Fatal(err)
+	if err := decoder.Decode(&b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	st := signaltranspiler.NewSignalTranspiler()
-	output, _ := st.Transpile(b.Input)
-	fmt.Printf("%+v\n", output)
-	bs, err := json.Marshal(output)
+	st, err := b.Options.newSignalTranspiler()
 	if err != nil {
-		log.Fatal(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	outputs, _ := st.TranspileAll(b.Input)
+	bs, err := json.Marshal(outputs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 	fmt.Fprintln(w, string(bs))
 }
 
 func runHandler(w http.ResponseWriter, r *http.Request) {
 	type body struct {
-		Input string `json:"input"`
+		Input   string            `json:"input"`
+		Options transpilerOptions `json:"options"`
 	}
 	decoder := json.NewDecoder(r.Body)
 	var b body
-	err := decoder.Decode(&b)
+	if err := decoder.Decode(&b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	st, err := b.Options.newSignalTranspiler()
 	if err != nil {
-		log.Fatal(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	outputs, _ := st.TranspileAll(b.Input)
 
-	st := signaltranspiler.NewSignalTranspiler()
-	output, _ := st.Transpile(b.Input)
+	result := signalrunner.NewSignalRunner(signalrunner.DefaultConcurrency).Run(r.Context(), outputs)
 
-	signalOutput, err := signalchecker.CheckSignal(output.SignalInput)
+	bs, err := json.Marshal(result)
 	if err != nil {
-		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	output.SignalOutput = signalOutput
 
-	bs, err := json.Marshal(output)
+	fmt.Fprintln(w, string(bs))
+}
+
+// batchHandler checks many signals concurrently. It accepts either a list of signal texts, one multi-signal document,
+// or both, and responds with every signal's result plus a summary.
+func batchHandler(w http.ResponseWriter, r *http.Request) {
+	type body struct {
+		Inputs      []string          `json:"inputs"`
+		Input       string            `json:"input"`
+		Concurrency int               `json:"concurrency"`
+		Options     transpilerOptions `json:"options"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	var b body
+	if err := decoder.Decode(&b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if b.Input != "" {
+		b.Inputs = append(b.Inputs, b.Input)
+	}
+
+	st, err := b.Options.newSignalTranspiler()
 	if err != nil {
-		log.Fatal(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	signals := []signaltranspiler.SignalTranspilerOutput{}
+	for _, input := range b.Inputs {
+		outputs, _ := st.TranspileAll(input)
+		signals = append(signals, outputs...)
 	}
 
+	result := signalrunner.NewSignalRunner(b.Concurrency).Run(r.Context(), signals)
+	if r.Context().Err() != nil {
+		log.Printf("batch cancelled: %v\n", r.Context().Err())
+		return
+	}
+
+	bs, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintln(w, string(bs))
+}
+
+// transpilerOptions are per-request overrides of the transpiler's defaults. Zero values keep the defaults.
+type transpilerOptions struct {
+	DefaultExchange       string   `json:"defaultExchange"`
+	DefaultTimeoutSeconds int      `json:"defaultTimeoutSeconds"`
+	MaxTimeoutSeconds     int      `json:"maxTimeoutSeconds"`
+	SupportedExchanges    []string `json:"supportedExchanges"`
+	Strict                bool     `json:"strict"`
+	Dialect               string   `json:"dialect"`
+	Extract               bool     `json:"extract"`
+
+	// MarketCatalog is a snapshot file to use instead of the embedded market catalog. Only the CLI sets it.
+	MarketCatalog string `json:"-"`
+}
+
+// marketCatalog is the catalog that serve loads from $MARKET_CATALOG, or nil for the embedded one.
+var marketCatalog *marketcatalog.Catalog
+
+// newSignalTranspiler fails if the options name a dialect that doesn't exist, a default timeout under a minute, or a
+// market catalog that can't be read.
+func (o transpilerOptions) newSignalTranspiler() (*signaltranspiler.SignalTranspiler, error) {
+	opts := []signaltranspiler.Option{signaltranspiler.WithStrictMode(o.Strict), signaltranspiler.WithExtraction(o.Extract)}
+	if o.Dialect != "" {
+		dialect, ok := signaltranspiler.LookupDialect(o.Dialect)
+		if !ok {
+			return nil, fmt.Errorf("unknown dialect %q, use one of %v", o.Dialect, strings.Join(signaltranspiler.DialectNames(), ", "))
+		}
+		opts = append(opts, signaltranspiler.WithDialect(dialect))
+	}
+	if o.DefaultExchange != "" {
+		opts = append(opts, signaltranspiler.WithDefaultExchange(o.DefaultExchange))
+	}
+	if o.DefaultTimeoutSeconds > 0 && o.DefaultTimeoutSeconds < 60 {
+		return nil, fmt.Errorf("default timeout of %v seconds is too short, timeouts are in whole minutes", o.DefaultTimeoutSeconds)
+	}
+	if o.DefaultTimeoutSeconds > 0 {
+		opts = append(opts, signaltranspiler.WithDefaultTimeout(time.Duration(o.DefaultTimeoutSeconds)*time.Second))
+	}
+	if o.MaxTimeoutSeconds > 0 {
+		opts = append(opts, signaltranspiler.WithMaxTimeout(time.Duration(o.MaxTimeoutSeconds)*time.Second))
+	}
+	if len(o.SupportedExchanges) > 0 {
+		opts = append(opts, signaltranspiler.WithSupportedExchanges(o.SupportedExchanges...))
+	}
+	switch {
+	case o.MarketCatalog != "":
+		catalog, err := marketcatalog.LoadFile(o.MarketCatalog)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, signaltranspiler.WithMarketCatalog(catalog))
+	case marketCatalog != nil:
+		opts = append(opts, signaltranspiler.WithMarketCatalog(marketCatalog))
+	}
+	return signaltranspiler.NewSignalTranspiler(opts...), nil
+}
+
+// formatHandler rewrites the input signals into normalized signal text.
+func formatHandler(w http.ResponseWriter, r *http.Request) {
+	type body struct {
+		Input    string            `json:"input"`
+		Inferred bool              `json:"inferred"`
+		Options  transpilerOptions `json:"options"`
+	}
+	type response struct {
+		Output string `json:"output"`
+		Error  string `json:"error,omitempty"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	var b body
+	if err := decoder.Decode(&b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	st, err := b.Options.newSignalTranspiler()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var resp response
+	formatted, err := st.Format(b.Input, b.Inferred)
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	resp.Output = formatted
+
+	bs, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintln(w, string(bs))
+}
+
+// decompileHandler renders a JSON signal check input back into signal text, e.g. to open it in the editor.
+func decompileHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Input string `json:"input"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	var input common.SignalCheckInput
+	if err := decoder.Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bs, err := json.Marshal(response{Input: signaltranspiler.Render(input)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintln(w, string(bs))
+}
+
+// reportHandler builds a performance report over signal check results that were obtained elsewhere.
+func reportHandler(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var outputs []common.SignalCheckOutput
+	if err := decoder.Decode(&outputs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bs, err := json.Marshal(signalrunner.NewReport(outputs))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	fmt.Fprintln(w, string(bs))
 }
 
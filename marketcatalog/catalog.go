@@ -0,0 +1,201 @@
+// Package marketcatalog knows which markets each exchange lists, from a JSON snapshot that is either embedded in the
+// binary or loaded from a file.
+package marketcatalog
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+//go:embed markets.json
+var snapshot []byte
+
+// Catalog is a snapshot of the markets that exchanges list, as "BASE/QUOTE" symbols by lowercase exchange name, e.g.
+// "binance": ["BTC/USDT", ...]. Exchanges that aren't in the snapshot are unknown, so every market is accepted on them.
+type Catalog struct {
+	UpdatedAt string              `json:"updatedAt"`
+	Exchanges map[string][]string `json:"exchanges"`
+
+	index  map[string]map[string]bool
+	assets map[string]bool
+}
+
+// Default returns a copy of the catalog embedded in the binary.
+func Default() *Catalog {
+	c, err := Load(bytes.NewReader(snapshot))
+	if err != nil {
+		panic(fmt.Sprintf("marketcatalog: embedded snapshot is invalid: %v", err))
+	}
+	return c
+}
+
+// Load reads a catalog snapshot.
+func Load(r io.Reader) (*Catalog, error) {
+	c := &Catalog{}
+	if err := json.NewDecoder(r).Decode(c); err != nil {
+		return nil, err
+	}
+	for exchange, symbols := range c.Exchanges {
+		for _, symbol := range symbols {
+			if parts := strings.Split(symbol, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return nil, fmt.Errorf("invalid symbol %q for exchange %v, e.g. BTC/USDT", symbol, exchange)
+			}
+		}
+	}
+	c.normalize()
+	return c, nil
+}
+
+// LoadFile reads a catalog snapshot from a file.
+func LoadFile(path string) (*Catalog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return c, nil
+}
+
+// Merge replaces the symbols of every exchange in other, and keeps the rest. UpdatedAt becomes other's, since that's
+// when the markets it brings were listed, unless other doesn't have one.
+func (c *Catalog) Merge(other *Catalog) {
+	if c.Exchanges == nil {
+		c.Exchanges = map[string][]string{}
+	}
+	for exchange, symbols := range other.Exchanges {
+		c.Exchanges[exchange] = symbols
+	}
+	if other.UpdatedAt != "" {
+		c.UpdatedAt = other.UpdatedAt
+	}
+	c.normalize()
+}
+
+// Write writes the catalog as a snapshot that Load reads back.
+func (c *Catalog) Write(w io.Writer) error {
+	bs, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(bs))
+	return err
+}
+
+// HasExchange reports whether the catalog knows the markets of the exchange.
+func (c *Catalog) HasExchange(exchange string) bool {
+	_, ok := c.index[strings.ToLower(exchange)]
+	return ok
+}
+
+// HasMarket reports whether the exchange lists the market. It's true for exchanges the catalog doesn't know.
+func (c *Catalog) HasMarket(exchange, base, quote string) bool {
+	symbols, ok := c.index[strings.ToLower(exchange)]
+	return !ok || symbols[symbol(base, quote)]
+}
+
+// HasAsset reports whether any exchange lists a market with the asset, as either its base or its quote.
+func (c *Catalog) HasAsset(asset string) bool {
+	return c.assets[strings.ToUpper(asset)]
+}
+
+// maxSuggestionDistance is how many single character edits away from a market Suggest looks, e.g. BTCX/USDQ is 2 away
+// from BTC/USDT. Further than that, the closest market is most likely a different one altogether.
+const maxSuggestionDistance = 2
+
+// Suggest returns the exchange's market closest to the given one, e.g. "BTC/USDT" for BTCX/USDQ, or "" if the
+// catalog doesn't know the exchange or no market is within maxSuggestionDistance. Base and quote are compared
+// separately, and ties go to the market listed first.
+func (c *Catalog) Suggest(exchange, base, quote string) string {
+	var (
+		closest  = ""
+		distance = maxSuggestionDistance + 1
+	)
+	for _, candidate := range c.Exchanges[strings.ToLower(exchange)] {
+		parts := strings.Split(candidate, "/")
+		d := levenshtein(strings.ToUpper(base), parts[0]) + levenshtein(strings.ToUpper(quote), parts[1])
+		if d < distance {
+			closest, distance = candidate, d
+		}
+	}
+	return closest
+}
+
+// normalize makes exchange names lowercase and symbols uppercase, drops duplicates, and rebuilds the indexes.
+func (c *Catalog) normalize() {
+	exchanges := map[string][]string{}
+	c.index = map[string]map[string]bool{}
+	c.assets = map[string]bool{}
+	for exchange, symbols := range c.Exchanges {
+		exchange = strings.ToLower(exchange)
+		if c.index[exchange] == nil {
+			c.index[exchange] = map[string]bool{}
+		}
+		for _, s := range symbols {
+			s = strings.ToUpper(strings.TrimSpace(s))
+			if c.index[exchange][s] {
+				continue
+			}
+			c.index[exchange][s] = true
+			for _, asset := range strings.Split(s, "/") {
+				c.assets[asset] = true
+			}
+			exchanges[exchange] = append(exchanges[exchange], s)
+		}
+	}
+	c.Exchanges = exchanges
+}
+
+// ExchangeNames returns the exchanges the catalog knows, sorted.
+func (c *Catalog) ExchangeNames() []string {
+	names := []string{}
+	for exchange := range c.Exchanges {
+		names = append(names, exchange)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func symbol(base, quote string) string {
+	return strings.ToUpper(base) + "/" + strings.ToUpper(quote)
+}
+
+// levenshtein is the number of single character edits that turn a into b.
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,190 @@
+package marketcatalog
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSnapshot = `{
+  "updatedAt": "2021-08-01T00:00:00Z",
+  "exchanges": {
+    "Binance": ["BTC/USDT", "eth/usdt", "BTC/USDT", "ETH/BTC"],
+    "kraken": ["BTC/USD", "ETH/USD"]
+  }
+}`
+
+func mustLoad(t *testing.T, snapshot string) *Catalog {
+	t.Helper()
+	c, err := Load(strings.NewReader(snapshot))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c
+}
+
+func TestLoad(t *testing.T) {
+	ts := []struct {
+		name        string
+		snapshot    string
+		expected    map[string][]string
+		expectedErr bool
+	}{
+		{
+			name:     "normalized",
+			snapshot: testSnapshot,
+			expected: map[string][]string{"binance": {"BTC/USDT", "ETH/USDT", "ETH/BTC"}, "kraken": {"BTC/USD", "ETH/USD"}},
+		},
+		{
+			name:        "symbol without a quote",
+			snapshot:    `{"exchanges": {"binance": ["BTCUSDT"]}}`,
+			expectedErr: true,
+		},
+		{
+			name:        "symbol with an empty base",
+			snapshot:    `{"exchanges": {"binance": ["/USDT"]}}`,
+			expectedErr: true,
+		},
+		{
+			name:        "not JSON",
+			snapshot:    `binance: BTC/USDT`,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := Load(strings.NewReader(tc.snapshot))
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(c.Exchanges, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, c.Exchanges)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	c := Default()
+	if !c.HasExchange("binance") || !c.HasMarket("binance", "BTC", "USDT") {
+		t.Errorf("expected the embedded catalog to list BTC/USDT on binance")
+	}
+}
+
+func TestHasMarket(t *testing.T) {
+	c := mustLoad(t, testSnapshot)
+	ts := []struct {
+		name                  string
+		exchange, base, quote string
+		expected              bool
+	}{
+		{name: "listed", exchange: "binance", base: "BTC", quote: "USDT", expected: true},
+		{name: "listed, in other case", exchange: "BINANCE", base: "eth", quote: "usdt", expected: true},
+		{name: "unlisted", exchange: "binance", base: "RVN", quote: "USDT", expected: false},
+		{name: "listed on another exchange", exchange: "kraken", base: "BTC", quote: "USDT", expected: false},
+		{name: "unknown exchange", exchange: "kucoin", base: "RVN", quote: "USDT", expected: true},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := c.HasMarket(tc.exchange, tc.base, tc.quote); actual != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestHasAsset(t *testing.T) {
+	c := mustLoad(t, testSnapshot)
+	ts := []struct {
+		asset    string
+		expected bool
+	}{
+		{asset: "BTC", expected: true},
+		{asset: "usdt", expected: true},
+		{asset: "RVN", expected: false},
+	}
+	for _, tc := range ts {
+		t.Run(tc.asset, func(t *testing.T) {
+			if actual := c.HasAsset(tc.asset); actual != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	c := mustLoad(t, testSnapshot)
+	ts := []struct {
+		name                  string
+		exchange, base, quote string
+		expected              string
+	}{
+		{name: "typo in the base", exchange: "binance", base: "BTX", quote: "USDT", expected: "BTC/USDT"},
+		{name: "typos in the base and quote", exchange: "binance", base: "BTCX", quote: "USDQ", expected: "BTC/USDT"},
+		{name: "tie goes to the market listed first", exchange: "kraken", base: "BTH", quote: "USD", expected: "BTC/USD"},
+		{name: "nothing close", exchange: "binance", base: "ABCDEF", quote: "GHIJKL", expected: ""},
+		{name: "one edit too many", exchange: "binance", base: "BTCXY", quote: "USDQ", expected: ""},
+		{name: "unknown exchange", exchange: "kucoin", base: "BTX", quote: "USDT", expected: ""},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := c.Suggest(tc.exchange, tc.base, tc.quote); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	ts := []struct {
+		name              string
+		source            string
+		expected          map[string][]string
+		expectedUpdatedAt string
+	}{
+		{
+			name:              "replaces an exchange and keeps the rest",
+			source:            `{"updatedAt": "2021-09-01T00:00:00Z", "exchanges": {"kraken": ["XBT/EUR"]}}`,
+			expected:          map[string][]string{"binance": {"BTC/USDT", "ETH/USDT", "ETH/BTC"}, "kraken": {"XBT/EUR"}},
+			expectedUpdatedAt: "2021-09-01T00:00:00Z",
+		},
+		{
+			name:              "adds an exchange",
+			source:            `{"updatedAt": "2021-09-01T00:00:00Z", "exchanges": {"KuCoin": ["btc/usdt"]}}`,
+			expected:          map[string][]string{"binance": {"BTC/USDT", "ETH/USDT", "ETH/BTC"}, "kraken": {"BTC/USD", "ETH/USD"}, "kucoin": {"BTC/USDT"}},
+			expectedUpdatedAt: "2021-09-01T00:00:00Z",
+		},
+		{
+			name:              "source without a date",
+			source:            `{"exchanges": {"kraken": ["XBT/EUR"]}}`,
+			expected:          map[string][]string{"binance": {"BTC/USDT", "ETH/USDT", "ETH/BTC"}, "kraken": {"XBT/EUR"}},
+			expectedUpdatedAt: "2021-08-01T00:00:00Z",
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			c := mustLoad(t, testSnapshot)
+			c.Merge(mustLoad(t, tc.source))
+			if !reflect.DeepEqual(c.Exchanges, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, c.Exchanges)
+			}
+			if c.UpdatedAt != tc.expectedUpdatedAt {
+				t.Errorf("expected updatedAt %v, got %v", tc.expectedUpdatedAt, c.UpdatedAt)
+			}
+
+			// What Write writes, Load reads back
+			var buf bytes.Buffer
+			if err := c.Write(&buf); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			written := mustLoad(t, buf.String())
+			if !reflect.DeepEqual(written.Exchanges, c.Exchanges) || written.UpdatedAt != c.UpdatedAt {
+				t.Errorf("expected %+v, got %+v", c, written)
+			}
+		})
+	}
+}
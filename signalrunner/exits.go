@@ -0,0 +1,161 @@
+package signalrunner
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marianogappa/hts/signaltranspiler"
+	"github.com/marianogappa/signal-checker/common"
+)
+
+// Events added to a check's output by exit rules that the signal checker doesn't support.
+const (
+	STOP_MOVED         = "stop_moved"
+	STOPPED_TRAILING   = "stopped_trailing"
+	STOPPED_BREAK_EVEN = "stopped_break_even"
+)
+
+// applyExitRules applies the signal's trailing stop and break even to the candlesticks of its check. Every time the
+// stop moves, it adds a stop_moved event. If the moved stop is reached, it adds the exit event and drops the checker's
+// events from then on, since the position was already closed. Like the checker, it's pessimistic: within a
+// candlestick, the price moves against the position first.
+func applyExitRules(signal signaltranspiler.SignalTranspilerOutput, output common.SignalCheckOutput) common.SignalCheckOutput {
+	if output.IsError || (signal.TrailingStopPercentage == 0 && signal.BreakEvenAfterTakeProfit == 0) {
+		return output
+	}
+	entered := -1
+	for i, event := range output.Events {
+		if event.EventType == common.ENTERED {
+			entered = i
+			break
+		}
+	}
+	if entered == -1 {
+		return output
+	}
+	enteredAt, err := output.Events[entered].At.Time()
+	if err != nil {
+		return output
+	}
+	closedAt := closedAt(output.Events)
+
+	var (
+		input      = output.Input
+		entryPrice = float64(output.Events[entered].Price)
+		peak       = entryPrice
+		stop       = float64(input.StopLoss)
+		hasStop    = stop > 0
+		movedBy    = ""
+		reached    = 0
+		events     = []common.SignalCheckOutputEvent{}
+		exitedAt   time.Time
+	)
+	// beyond is true if price is past the stop in the direction the position makes money
+	beyond := func(price, stop float64) bool {
+		if input.IsShort {
+			return price < stop
+		}
+		return price > stop
+	}
+	moveStop := func(price float64, reason string, at time.Time) {
+		price = math.Round(price*1e8) / 1e8
+		if hasStop && !beyond(price, stop) {
+			return
+		}
+		stop, hasStop, movedBy = price, true, reason
+		events = append(events, common.SignalCheckOutputEvent{EventType: STOP_MOVED, Price: common.JsonFloat64(price), At: iso8601(at)})
+	}
+	for _, candlestick := range output.Candlesticks {
+		at := time.Unix(int64(candlestick.Timestamp), 0).UTC()
+		// The candlestick where the position was entered is left to the checker, since it's unknown when in it the
+		// position was entered
+		if !at.After(enteredAt) {
+			continue
+		}
+		if !closedAt.IsZero() && at.After(closedAt) {
+			break
+		}
+		adverse, favorable := float64(candlestick.LowestPrice), float64(candlestick.HighestPrice)
+		if input.IsShort {
+			adverse, favorable = favorable, adverse
+		}
+		if movedBy != "" && !beyond(adverse, stop) {
+			events = append(events, common.SignalCheckOutputEvent{EventType: movedBy, Price: common.JsonFloat64(stop), At: iso8601(at)})
+			exitedAt = at
+			break
+		}
+		for reached < len(input.TakeProfits) && !beyond(float64(input.TakeProfits[reached]), favorable) {
+			reached++
+		}
+		if signal.BreakEvenAfterTakeProfit > 0 && reached >= signal.BreakEvenAfterTakeProfit {
+			moveStop(entryPrice, STOPPED_BREAK_EVEN, at)
+		}
+		if signal.TrailingStopPercentage > 0 {
+			if beyond(favorable, peak) {
+				peak = favorable
+			}
+			distance := float64(signal.TrailingStopPercentage) / 100
+			if input.IsShort {
+				moveStop(peak*(1+distance), STOPPED_TRAILING, at)
+			} else {
+				moveStop(peak*(1-distance), STOPPED_TRAILING, at)
+			}
+		}
+	}
+	if len(events) == 0 {
+		return output
+	}
+
+	// The checker's events after the exit didn't happen. The ones left go first, so that e.g. a take profit comes
+	// before the stop move it caused.
+	merged := []common.SignalCheckOutputEvent{}
+	for _, event := range output.Events {
+		eventAt, err := event.At.Time()
+		if !exitedAt.IsZero() && event.EventType != common.ENTERED && (err != nil || !eventAt.Before(exitedAt)) {
+			continue
+		}
+		merged = append(merged, event)
+	}
+	events = append(merged, events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At < events[j].At })
+	output.Events = events
+	output.HighestTakeProfit = 0
+	for _, event := range events {
+		if isTakenProfit(event.EventType) {
+			output.HighestTakeProfit++
+		}
+	}
+	if !exitedAt.IsZero() {
+		output.ReachedStopLoss = false
+	}
+	return output
+}
+
+// closedAt is when the checker, or a moved stop, closed the position, or zero if nothing did.
+func closedAt(events []common.SignalCheckOutputEvent) time.Time {
+	for _, event := range events {
+		if isClosingEvent(event.EventType) {
+			at, _ := event.At.Time()
+			return at
+		}
+	}
+	return time.Time{}
+}
+
+func isClosingEvent(eventType string) bool {
+	return eventType == common.STOPPED_LOSS || eventType == common.INVALIDATED || isMovedStopExit(eventType)
+}
+
+func isMovedStopExit(eventType string) bool {
+	return eventType == STOPPED_TRAILING || eventType == STOPPED_BREAK_EVEN
+}
+
+func isTakenProfit(eventType string) bool {
+	return strings.HasPrefix(eventType, common.TAKEN_PROFIT_)
+}
+
+func iso8601(t time.Time) common.ISO8601 {
+	return common.ISO8601(t.Format(time.RFC3339))
+}
@@ -0,0 +1,114 @@
+package signalrunner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/hts/signaltranspiler"
+	"github.com/marianogappa/signal-checker/common"
+)
+
+var start = time.Date(2021, 6, 22, 15, 21, 0, 0, time.UTC)
+
+// candlestick is the minute candlestick that starts the given number of minutes after start.
+func candlestick(minute int, low, high float64) common.Candlestick {
+	return common.Candlestick{Timestamp: int(start.Add(time.Duration(minute) * time.Minute).Unix()), LowestPrice: common.JsonFloat64(low), HighestPrice: common.JsonFloat64(high)}
+}
+
+// event is the event at the start of the minute candlestick that starts the given number of minutes after start.
+func event(eventType string, price float64, minute int) common.SignalCheckOutputEvent {
+	return common.SignalCheckOutputEvent{EventType: eventType, Price: common.JsonFloat64(price), At: iso8601(start.Add(time.Duration(minute) * time.Minute))}
+}
+
+func TestApplyExitRules(t *testing.T) {
+	ts := []struct {
+		name                      string
+		signal                    signaltranspiler.SignalTranspilerOutput
+		output                    common.SignalCheckOutput
+		expected                  []common.SignalCheckOutputEvent
+		expectedHighestTakeProfit int
+	}{
+		{
+			name:   "trailing stop on a long, with the checker's later events dropped",
+			signal: signaltranspiler.SignalTranspilerOutput{TrailingStopPercentage: 10},
+			output: common.SignalCheckOutput{
+				Input:        common.SignalCheckInput{StopLoss: -1, TakeProfits: []common.JsonFloat64{130}},
+				Candlesticks: []common.Candlestick{candlestick(0, 99, 101), candlestick(1, 99, 110), candlestick(2, 100, 120), candlestick(3, 105, 115), candlestick(4, 110, 130)},
+				Events:       []common.SignalCheckOutputEvent{event(common.ENTERED, 100, 0), event(common.TAKEN_PROFIT_+"1", 130, 4)},
+			},
+			expected: []common.SignalCheckOutputEvent{
+				event(common.ENTERED, 100, 0),
+				event(STOP_MOVED, 99, 1),
+				event(STOP_MOVED, 108, 2),
+				event(STOPPED_TRAILING, 108, 3),
+			},
+		},
+		{
+			name:   "trailing stop on a short that isn't reached",
+			signal: signaltranspiler.SignalTranspilerOutput{TrailingStopPercentage: 5},
+			output: common.SignalCheckOutput{
+				Input:        common.SignalCheckInput{IsShort: true, StopLoss: -1, TakeProfits: []common.JsonFloat64{80}},
+				Candlesticks: []common.Candlestick{candlestick(0, 99, 101), candlestick(1, 95, 99), candlestick(2, 96, 99)},
+				Events:       []common.SignalCheckOutputEvent{event(common.ENTERED, 100, 0)},
+			},
+			expected: []common.SignalCheckOutputEvent{
+				event(common.ENTERED, 100, 0),
+				event(STOP_MOVED, 99.75, 1),
+			},
+		},
+		{
+			name:   "break even after the first take profit, instead of the stop loss",
+			signal: signaltranspiler.SignalTranspilerOutput{BreakEvenAfterTakeProfit: 1},
+			output: common.SignalCheckOutput{
+				Input:           common.SignalCheckInput{StopLoss: 90, TakeProfits: []common.JsonFloat64{110, 120}},
+				Candlesticks:    []common.Candlestick{candlestick(0, 99, 101), candlestick(1, 101, 111), candlestick(2, 100.5, 105), candlestick(3, 89, 102)},
+				Events:          []common.SignalCheckOutputEvent{event(common.ENTERED, 100, 0), event(common.TAKEN_PROFIT_+"1", 110, 1), event(common.STOPPED_LOSS, 90, 3)},
+				ReachedStopLoss: true,
+			},
+			expected: []common.SignalCheckOutputEvent{
+				event(common.ENTERED, 100, 0),
+				event(common.TAKEN_PROFIT_+"1", 110, 1),
+				event(STOP_MOVED, 100, 1),
+				event(STOPPED_BREAK_EVEN, 100, 3),
+			},
+			expectedHighestTakeProfit: 1,
+		},
+		{
+			name:   "break even after a take profit that isn't reached",
+			signal: signaltranspiler.SignalTranspilerOutput{BreakEvenAfterTakeProfit: 2},
+			output: common.SignalCheckOutput{
+				Input:             common.SignalCheckInput{StopLoss: 90, TakeProfits: []common.JsonFloat64{110, 120}},
+				Candlesticks:      []common.Candlestick{candlestick(0, 99, 101), candlestick(1, 101, 111), candlestick(2, 89, 102)},
+				Events:            []common.SignalCheckOutputEvent{event(common.ENTERED, 100, 0), event(common.TAKEN_PROFIT_+"1", 110, 1), event(common.STOPPED_LOSS, 90, 2)},
+				HighestTakeProfit: 1,
+			},
+			expected:                  []common.SignalCheckOutputEvent{event(common.ENTERED, 100, 0), event(common.TAKEN_PROFIT_+"1", 110, 1), event(common.STOPPED_LOSS, 90, 2)},
+			expectedHighestTakeProfit: 1,
+		},
+		{
+			name:   "not entered",
+			signal: signaltranspiler.SignalTranspilerOutput{TrailingStopPercentage: 10},
+			output: common.SignalCheckOutput{
+				Input:        common.SignalCheckInput{StopLoss: -1, TakeProfits: []common.JsonFloat64{130}},
+				Candlesticks: []common.Candlestick{candlestick(0, 120, 130), candlestick(1, 110, 120)},
+				Events:       []common.SignalCheckOutputEvent{event(common.INVALIDATED, 115, 1)},
+			},
+			expected: []common.SignalCheckOutputEvent{event(common.INVALIDATED, 115, 1)},
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			output := applyExitRules(tc.signal, tc.output)
+			if !reflect.DeepEqual(output.Events, tc.expected) {
+				t.Errorf("expected events %+v\ngot             %+v", tc.expected, output.Events)
+			}
+			if output.HighestTakeProfit != tc.expectedHighestTakeProfit {
+				t.Errorf("expected highest take profit %v, got %v", tc.expectedHighestTakeProfit, output.HighestTakeProfit)
+			}
+			if exited := isMovedStopExit(output.Events[len(output.Events)-1].EventType); exited && output.ReachedStopLoss {
+				t.Errorf("expected the stop loss not to be reached after an exit by a moved stop")
+			}
+		})
+	}
+}
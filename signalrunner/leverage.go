@@ -0,0 +1,100 @@
+package signalrunner
+
+import (
+	"math"
+	"time"
+
+	"github.com/marianogappa/hts/signaltranspiler"
+	"github.com/marianogappa/signal-checker/common"
+)
+
+// LIQUIDATED is added to a check's output when a leveraged position loses its whole margin.
+const LIQUIDATED = "liquidated"
+
+// applyLiquidation checks whether the candlesticks of a leveraged signal's check reached its liquidation price while
+// the position was open. If they did, it adds a liquidated event and drops the events from then on, like
+// applyExitRules does for a moved stop. It returns the output and the liquidation price, which is 0 for signals
+// without leverage or that weren't entered.
+func applyLiquidation(signal signaltranspiler.SignalTranspilerOutput, output common.SignalCheckOutput) (common.SignalCheckOutput, common.JsonFloat64) {
+	if output.IsError || signal.Leverage == 0 {
+		return output, 0
+	}
+	entered := -1
+	for i, event := range output.Events {
+		if event.EventType == common.ENTERED {
+			entered = i
+			break
+		}
+	}
+	if entered == -1 {
+		return output, 0
+	}
+	enteredAt, err := output.Events[entered].At.Time()
+	if err != nil {
+		return output, 0
+	}
+	var (
+		isShort     = output.Input.IsShort
+		liquidation = signaltranspiler.LiquidationPrice(float64(output.Events[entered].Price), float64(signal.Leverage), isShort)
+		closedAt    = closedAt(output.Events)
+		closedPrice = closedPrice(output.Events)
+		liquidated  time.Time
+	)
+	liquidation = math.Round(liquidation*1e8) / 1e8
+	for _, candlestick := range output.Candlesticks {
+		at := time.Unix(int64(candlestick.Timestamp), 0).UTC()
+		// As in applyExitRules, the candlestick where the position was entered is left out
+		if !at.After(enteredAt) {
+			continue
+		}
+		if !closedAt.IsZero() && at.After(closedAt) {
+			break
+		}
+		// In the candlestick where the position was closed, it was only liquidated if the liquidation price comes first
+		if at.Equal(closedAt) && closedPrice != 0 && ((!isShort && liquidation <= closedPrice) || (isShort && liquidation >= closedPrice)) {
+			break
+		}
+		if (!isShort && float64(candlestick.LowestPrice) <= liquidation) || (isShort && float64(candlestick.HighestPrice) >= liquidation) {
+			liquidated = at
+			break
+		}
+	}
+	if liquidated.IsZero() {
+		return output, common.JsonFloat64(liquidation)
+	}
+
+	events := []common.SignalCheckOutputEvent{}
+	output.HighestTakeProfit = 0
+	for _, event := range output.Events {
+		eventAt, err := event.At.Time()
+		if event.EventType != common.ENTERED && (err != nil || !eventAt.Before(liquidated)) {
+			continue
+		}
+		if isTakenProfit(event.EventType) {
+			output.HighestTakeProfit++
+		}
+		events = append(events, event)
+	}
+	output.Events = append(events, common.SignalCheckOutputEvent{EventType: LIQUIDATED, Price: common.JsonFloat64(liquidation), At: iso8601(liquidated)})
+	output.ReachedStopLoss = false
+	return output, common.JsonFloat64(liquidation)
+}
+
+// closedPrice is the price at which the position was closed (see closedAt), or zero if it wasn't.
+func closedPrice(events []common.SignalCheckOutputEvent) float64 {
+	for _, event := range events {
+		if isClosingEvent(event.EventType) {
+			return float64(event.Price)
+		}
+	}
+	return 0
+}
+
+// LeveragedProfitRatio is the signal's RealizedProfitRatio multiplied by its leverage. A position can't lose more
+// than its margin, so it's never below -1. It's 0 for signals without leverage.
+func LeveragedProfitRatio(signal signaltranspiler.SignalTranspilerOutput) common.JsonFloat64 {
+	if signal.Leverage == 0 {
+		return 0
+	}
+	return common.JsonFloat64(math.Max(-1, float64(signal.RealizedProfitRatio*signal.Leverage)))
+}
@@ -0,0 +1,117 @@
+package signalrunner
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/marianogappa/hts/signaltranspiler"
+	"github.com/marianogappa/signal-checker/common"
+)
+
+func TestApplyLiquidation(t *testing.T) {
+	ts := []struct {
+		name                      string
+		leverage                  common.JsonFloat64
+		output                    common.SignalCheckOutput
+		expected                  []common.SignalCheckOutputEvent
+		expectedLiquidation       common.JsonFloat64
+		expectedHighestTakeProfit int
+	}{
+		{
+			name:     "long liquidated before the stop loss",
+			leverage: 10,
+			output: common.SignalCheckOutput{
+				Input:           common.SignalCheckInput{StopLoss: 85, TakeProfits: []common.JsonFloat64{120}},
+				Candlesticks:    []common.Candlestick{candlestick(0, 99, 101), candlestick(1, 95, 105), candlestick(2, 89, 100), candlestick(3, 84, 90)},
+				Events:          []common.SignalCheckOutputEvent{event(common.ENTERED, 100, 0), event(common.STOPPED_LOSS, 85, 3)},
+				ReachedStopLoss: true,
+			},
+			expected:            []common.SignalCheckOutputEvent{event(common.ENTERED, 100, 0), event(LIQUIDATED, 90, 2)},
+			expectedLiquidation: 90,
+		},
+		{
+			name:     "long stopped before the liquidation price in the same candlestick",
+			leverage: 10,
+			output: common.SignalCheckOutput{
+				Input:           common.SignalCheckInput{StopLoss: 92, TakeProfits: []common.JsonFloat64{120}},
+				Candlesticks:    []common.Candlestick{candlestick(0, 99, 101), candlestick(1, 95, 105), candlestick(2, 89, 100)},
+				Events:          []common.SignalCheckOutputEvent{event(common.ENTERED, 100, 0), event(common.STOPPED_LOSS, 92, 2)},
+				ReachedStopLoss: true,
+			},
+			expected:            []common.SignalCheckOutputEvent{event(common.ENTERED, 100, 0), event(common.STOPPED_LOSS, 92, 2)},
+			expectedLiquidation: 90,
+		},
+		{
+			name:     "short liquidated in the candlestick of a take profit",
+			leverage: 20,
+			output: common.SignalCheckOutput{
+				Input:             common.SignalCheckInput{IsShort: true, StopLoss: -1, TakeProfits: []common.JsonFloat64{95}},
+				Candlesticks:      []common.Candlestick{candlestick(0, 99, 101), candlestick(1, 94, 106)},
+				Events:            []common.SignalCheckOutputEvent{event(common.ENTERED, 100, 0), event(common.TAKEN_PROFIT_+"1", 95, 1)},
+				HighestTakeProfit: 1,
+			},
+			expected:            []common.SignalCheckOutputEvent{event(common.ENTERED, 100, 0), event(LIQUIDATED, 105, 1)},
+			expectedLiquidation: 105,
+		},
+		{
+			name:     "without leverage",
+			leverage: 0,
+			output: common.SignalCheckOutput{
+				Input:        common.SignalCheckInput{StopLoss: -1, TakeProfits: []common.JsonFloat64{120}},
+				Candlesticks: []common.Candlestick{candlestick(0, 99, 101), candlestick(1, 10, 100)},
+				Events:       []common.SignalCheckOutputEvent{event(common.ENTERED, 100, 0)},
+			},
+			expected: []common.SignalCheckOutputEvent{event(common.ENTERED, 100, 0)},
+		},
+		{
+			name:     "not entered",
+			leverage: 10,
+			output: common.SignalCheckOutput{
+				Input:        common.SignalCheckInput{StopLoss: -1, TakeProfits: []common.JsonFloat64{120}},
+				Candlesticks: []common.Candlestick{candlestick(0, 99, 101), candlestick(1, 10, 100)},
+				Events:       []common.SignalCheckOutputEvent{event(common.INVALIDATED, 50, 1)},
+			},
+			expected: []common.SignalCheckOutputEvent{event(common.INVALIDATED, 50, 1)},
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			output, liquidation := applyLiquidation(signaltranspiler.SignalTranspilerOutput{Leverage: tc.leverage}, tc.output)
+			if liquidation != tc.expectedLiquidation {
+				t.Errorf("expected liquidation price %v, got %v", tc.expectedLiquidation, liquidation)
+			}
+			if !reflect.DeepEqual(output.Events, tc.expected) {
+				t.Errorf("expected events %+v\ngot             %+v", tc.expected, output.Events)
+			}
+			if output.HighestTakeProfit != tc.expectedHighestTakeProfit {
+				t.Errorf("expected highest take profit %v, got %v", tc.expectedHighestTakeProfit, output.HighestTakeProfit)
+			}
+			if output.Events[len(output.Events)-1].EventType == LIQUIDATED && output.ReachedStopLoss {
+				t.Errorf("expected the stop loss not to be reached after a liquidation")
+			}
+		})
+	}
+}
+
+func TestLeveragedProfitRatio(t *testing.T) {
+	ts := []struct {
+		name                string
+		leverage            common.JsonFloat64
+		realizedProfitRatio common.JsonFloat64
+		expected            float64
+	}{
+		{name: "profit", leverage: 10, realizedProfitRatio: 0.05, expected: 0.5},
+		{name: "loss", leverage: 10, realizedProfitRatio: -0.05, expected: -0.5},
+		{name: "loss over the margin", leverage: 10, realizedProfitRatio: -0.2, expected: -1},
+		{name: "without leverage", leverage: 0, realizedProfitRatio: 0.05, expected: 0},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			signal := signaltranspiler.SignalTranspilerOutput{Leverage: tc.leverage, RealizedProfitRatio: tc.realizedProfitRatio}
+			if actual := float64(LeveragedProfitRatio(signal)); math.Abs(actual-tc.expected) > 1e-9 {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package signalrunner
+
+import (
+	"github.com/marianogappa/signal-checker/common"
+	"github.com/marianogappa/signal-checker/profitcalculator"
+)
+
+// RealizedProfitRatio is the profit ratio of following a signal. It's the checker's ProfitRatio, which already
+// accounts for take profit allocations, unless a trailing stop, break even (see applyExitRules) or liquidation (see
+// applyLiquidation) closed the position before the checker did. Then the events go through the checker's profit
+// calculator again, with that exit as the stop loss, so that both are worked out the same way.
+func RealizedProfitRatio(output common.SignalCheckOutput) common.JsonFloat64 {
+	if output.IsError || !hasRunnerExit(output.Events) {
+		return output.ProfitRatio
+	}
+	calculator := profitcalculator.NewProfitCalculator(output.Input)
+	for _, event := range output.Events {
+		switch {
+		case isMovedStopExit(event.EventType), event.EventType == LIQUIDATED:
+			event.EventType = common.STOPPED_LOSS
+		case event.EventType == STOP_MOVED:
+			continue
+		}
+		calculator.ApplyEvent(event)
+	}
+	return common.JsonFloat64(calculator.CalculateTakeProfitRatio())
+}
+
+// hasRunnerExit reports whether signalrunner, rather than the checker, closed the position.
+func hasRunnerExit(events []common.SignalCheckOutputEvent) bool {
+	for _, event := range events {
+		if isMovedStopExit(event.EventType) || event.EventType == LIQUIDATED {
+			return true
+		}
+	}
+	return false
+}
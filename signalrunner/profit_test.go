@@ -0,0 +1,73 @@
+package signalrunner
+
+import (
+	"math"
+	"testing"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+func TestRealizedProfitRatio(t *testing.T) {
+	var (
+		entered = common.SignalCheckOutputEvent{EventType: common.ENTERED, Price: 100, At: "2021-06-22T15:21:00Z"}
+		tp1     = common.SignalCheckOutputEvent{EventType: common.TAKEN_PROFIT_ + "1", Price: 110, At: "2021-06-22T15:22:00Z"}
+		moved   = common.SignalCheckOutputEvent{EventType: STOP_MOVED, Price: 100, At: "2021-06-22T15:22:00Z"}
+	)
+	ts := []struct {
+		name     string
+		output   common.SignalCheckOutput
+		expected float64
+	}{
+		{
+			name: "closed by the checker",
+			output: common.SignalCheckOutput{
+				Events:      []common.SignalCheckOutputEvent{entered, tp1},
+				ProfitRatio: 0.1,
+			},
+			expected: 0.1,
+		},
+		{
+			name: "errored",
+			output: common.SignalCheckOutput{
+				IsError:     true,
+				ProfitRatio: 0.5,
+			},
+			expected: 0.5,
+		},
+		{
+			name: "stopped at break even after the first of two take profits with half each",
+			output: common.SignalCheckOutput{
+				Input: common.SignalCheckInput{TakeProfits: []common.JsonFloat64{110, 120}, TakeProfitRatios: []common.JsonFloat64{0.5, 0.5}},
+				Events: []common.SignalCheckOutputEvent{entered, tp1, moved,
+					{EventType: STOPPED_BREAK_EVEN, Price: 100, At: "2021-06-22T15:23:00Z"}},
+			},
+			expected: 0.5 * 0.1,
+		},
+		{
+			name: "trailing stop on a short without allocations",
+			output: common.SignalCheckOutput{
+				Input: common.SignalCheckInput{IsShort: true, TakeProfits: []common.JsonFloat64{90}},
+				Events: []common.SignalCheckOutputEvent{entered,
+					{EventType: STOP_MOVED, Price: 96, At: "2021-06-22T15:22:00Z"},
+					{EventType: STOPPED_TRAILING, Price: 96, At: "2021-06-22T15:23:00Z"}},
+			},
+			expected: 100.0/96 - 1,
+		},
+		{
+			name: "liquidated",
+			output: common.SignalCheckOutput{
+				Input: common.SignalCheckInput{TakeProfits: []common.JsonFloat64{110}},
+				Events: []common.SignalCheckOutputEvent{entered,
+					{EventType: LIQUIDATED, Price: 90, At: "2021-06-22T15:23:00Z"}},
+			},
+			expected: -0.1,
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := float64(RealizedProfitRatio(tc.output)); math.Abs(actual-tc.expected) > 1e-9 {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package signalrunner
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+// Report describes how good a set of signals (e.g. from one signal provider) would have been to follow. All rates are
+// ratios over the signals that entered, because signals that never entered have no outcome. Profit ratios are
+// realized, so they account for take profit allocations (see RealizedProfitRatio).
+type Report struct {
+	Signals            int                  `json:"signals"`
+	Entered            int                  `json:"entered"`
+	WinRate            common.JsonFloat64   `json:"winRate"`
+	AverageProfitRatio common.JsonFloat64   `json:"averageProfitRatio"`
+	MedianProfitRatio  common.JsonFloat64   `json:"medianProfitRatio"`
+	TakeProfitHitRates []common.JsonFloat64 `json:"takeProfitHitRates"`
+	StopLossRate       common.JsonFloat64   `json:"stopLossRate"`
+	TimeoutRate        common.JsonFloat64   `json:"timeoutRate"`
+	BestTrade          *ReportTrade         `json:"bestTrade,omitempty"`
+	WorstTrade         *ReportTrade         `json:"worstTrade,omitempty"`
+}
+
+// ReportTrade identifies a single signal within a report. Index is the signal's position in the report's input.
+type ReportTrade struct {
+	Index          int                `json:"index"`
+	Market         string             `json:"market"`
+	InitialISO8601 common.ISO8601     `json:"initialISO8601"`
+	ProfitRatio    common.JsonFloat64 `json:"profitRatio"`
+}
+
+// NewReport builds a report over signal check results. Errored results are counted as signals but otherwise ignored.
+func NewReport(outputs []common.SignalCheckOutput) Report {
+	report := Report{Signals: len(outputs), TakeProfitHitRates: []common.JsonFloat64{}}
+	var (
+		profitRatios      = []float64{}
+		takeProfitHits    = []int{}
+		wins              int
+		stopLosses        int
+		timeouts          int
+		profitRatioSum    float64
+		bestIdx, worstIdx = -1, -1
+	)
+	for i, output := range outputs {
+		if output.IsError || !output.Entered {
+			continue
+		}
+		report.Entered++
+		profitRatio := float64(RealizedProfitRatio(output))
+		profitRatios = append(profitRatios, profitRatio)
+		profitRatioSum += profitRatio
+		if profitRatio > 0 {
+			wins++
+		}
+		if output.ReachedStopLoss {
+			stopLosses++
+		}
+		if hasEvent(output.Events, common.INVALIDATED) {
+			timeouts++
+		}
+		for len(takeProfitHits) < len(output.Input.TakeProfits) {
+			takeProfitHits = append(takeProfitHits, 0)
+		}
+		for tp := 0; tp < output.HighestTakeProfit && tp < len(takeProfitHits); tp++ {
+			takeProfitHits[tp]++
+		}
+		if bestIdx == -1 || profitRatio > float64(RealizedProfitRatio(outputs[bestIdx])) {
+			bestIdx = i
+		}
+		if worstIdx == -1 || profitRatio < float64(RealizedProfitRatio(outputs[worstIdx])) {
+			worstIdx = i
+		}
+	}
+	if report.Entered == 0 {
+		return report
+	}
+
+	entered := float64(report.Entered)
+	report.WinRate = common.JsonFloat64(float64(wins) / entered)
+	report.AverageProfitRatio = common.JsonFloat64(profitRatioSum / entered)
+	report.MedianProfitRatio = common.JsonFloat64(median(profitRatios))
+	report.StopLossRate = common.JsonFloat64(float64(stopLosses) / entered)
+	report.TimeoutRate = common.JsonFloat64(float64(timeouts) / entered)
+	for _, hits := range takeProfitHits {
+		report.TakeProfitHitRates = append(report.TakeProfitHitRates, common.JsonFloat64(float64(hits)/entered))
+	}
+	report.BestTrade = newReportTrade(bestIdx, outputs[bestIdx])
+	report.WorstTrade = newReportTrade(worstIdx, outputs[worstIdx])
+	return report
+}
+
+func newReportTrade(i int, output common.SignalCheckOutput) *ReportTrade {
+	return &ReportTrade{
+		Index:          i,
+		Market:         strings.ToUpper(output.Input.BaseAsset + "/" + output.Input.QuoteAsset),
+		InitialISO8601: output.Input.InitialISO8601,
+		ProfitRatio:    RealizedProfitRatio(output),
+	}
+}
+
+func hasEvent(events []common.SignalCheckOutputEvent, eventType string) bool {
+	for _, event := range events {
+		if event.EventType == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func median(fs []float64) float64 {
+	sorted := append([]float64{}, fs...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
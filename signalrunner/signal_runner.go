@@ -0,0 +1,209 @@
+// Package signalrunner checks transpiled signals against exchange data, many at a time.
+package signalrunner
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/marianogappa/hts/signaltranspiler"
+	"github.com/marianogappa/signal-checker/binance"
+	"github.com/marianogappa/signal-checker/binanceusdmfutures"
+	"github.com/marianogappa/signal-checker/coinbase"
+	"github.com/marianogappa/signal-checker/common"
+	"github.com/marianogappa/signal-checker/ftx"
+	"github.com/marianogappa/signal-checker/kraken"
+	"github.com/marianogappa/signal-checker/kucoin"
+	"github.com/marianogappa/signal-checker/signalchecker"
+)
+
+var (
+	exchanges = map[string]common.Exchange{
+		common.BINANCE:              binance.NewBinance(),
+		common.FTX:                  ftx.NewFTX(),
+		common.COINBASE:             coinbase.NewCoinbase(),
+		common.KRAKEN:               kraken.NewKraken(),
+		common.KUCOIN:               kucoin.NewKucoin(),
+		common.BINANCE_USDM_FUTURES: binanceusdmfutures.NewBinanceUSDMFutures(),
+	}
+)
+
+const (
+	DefaultConcurrency = 4
+	MaxConcurrency     = 16
+)
+
+type SignalRunner struct {
+	concurrency int
+	checkSignal func(common.SignalCheckInput) (common.SignalCheckOutput, error)
+	priceAt     func(context.Context, common.SignalCheckInput) (common.JsonFloat64, error)
+}
+
+// NewSignalRunner builds a runner that checks at most concurrency signals at the same time. Values outside of
+// [1, MaxConcurrency] are clamped.
+func NewSignalRunner(concurrency int) *SignalRunner {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	if concurrency > MaxConcurrency {
+		concurrency = MaxConcurrency
+	}
+	return &SignalRunner{concurrency: concurrency, checkSignal: signalchecker.CheckSignal, priceAt: firstCandlePrice}
+}
+
+type BatchOutput struct {
+	Signals []signaltranspiler.SignalTranspilerOutput `json:"signals"`
+	Summary BatchSummary                              `json:"summary"`
+	Report  Report                                    `json:"report"`
+}
+
+type BatchSummary struct {
+	Total              int                `json:"total"`
+	TranspileErrors    int                `json:"transpileErrors"`
+	CheckErrors        int                `json:"checkErrors"`
+	Cancelled          int                `json:"cancelled"`
+	Checked            int                `json:"checked"`
+	Entered            int                `json:"entered"`
+	TookProfit         int                `json:"tookProfit"`
+	StoppedLoss        int                `json:"stoppedLoss"`
+	AverageProfitRatio common.JsonFloat64 `json:"averageProfitRatio"`
+}
+
+// Run checks every signal without transpiling errors, and fills in its SignalOutput. When ctx is done, signals that
+// haven't finished checking are marked as Cancelled, with the context's error as their SignalOutput's error, and Run
+// returns without waiting for in-flight checks.
+func (r SignalRunner) Run(ctx context.Context, signals []signaltranspiler.SignalTranspilerOutput) BatchOutput {
+	var (
+		jobs = make(chan int)
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < r.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if signals[i].HasPendingPercentages() {
+					price, err := r.priceAt(ctx, signals[i].SignalInput)
+					if ctx.Err() != nil {
+						signals[i].SignalOutput, signals[i].Cancelled = cancelledOutput(ctx, signals[i].SignalInput), true
+						continue
+					}
+					if err != nil {
+						signals[i].SignalOutput = common.SignalCheckOutput{Input: signals[i].SignalInput, IsError: true, HttpStatus: 500, ErrorMessage: err.Error()}
+						continue
+					}
+					signals[i].ResolvePercentages(price)
+				}
+				output, cancelled := r.check(ctx, signals[i].SignalInput)
+				if cancelled {
+					signals[i].SignalOutput, signals[i].Cancelled = output, true
+					continue
+				}
+				output = applyExitRules(signals[i], output)
+				signals[i].SignalOutput, signals[i].LiquidationPrice = applyLiquidation(signals[i], output)
+				signals[i].RealizedProfitRatio = RealizedProfitRatio(signals[i].SignalOutput)
+				signals[i].LeveragedProfitRatio = LeveragedProfitRatio(signals[i])
+			}
+		}()
+	}
+	for i := range signals {
+		if len(signals[i].Errors) > 0 {
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	signalOutputs := []common.SignalCheckOutput{}
+	for _, signal := range signals {
+		signalOutputs = append(signalOutputs, signal.SignalOutput)
+	}
+	return BatchOutput{Signals: signals, Summary: summarize(signals), Report: NewReport(signalOutputs)}
+}
+
+// check runs a single signal check, giving up when ctx is done, in which case it returns true. The signal checker
+// can't be interrupted, so an abandoned check keeps running in the background until it finishes, but its result is
+// discarded.
+func (r SignalRunner) check(ctx context.Context, input common.SignalCheckInput) (common.SignalCheckOutput, bool) {
+	if ctx.Err() != nil {
+		return cancelledOutput(ctx, input), true
+	}
+	result := make(chan common.SignalCheckOutput, 1)
+	go func() {
+		output, _ := r.checkSignal(input)
+		result <- output
+	}()
+	select {
+	case output := <-result:
+		return output, false
+	case <-ctx.Done():
+		return cancelledOutput(ctx, input), true
+	}
+}
+
+// firstCandlePrice is the price that percentage take profits and stop loss are relative to when a signal enters
+// immediately: the open price of the first candlestick at or after the signal's start. Like check, it gives up when
+// ctx is done, and leaves the request running in the background.
+func firstCandlePrice(ctx context.Context, input common.SignalCheckInput) (common.JsonFloat64, error) {
+	exchange, ok := exchanges[strings.ToLower(input.Exchange)]
+	if !ok {
+		return 0, common.ErrInvalidExchange
+	}
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	type priceResult struct {
+		price common.JsonFloat64
+		err   error
+	}
+	result := make(chan priceResult, 1)
+	go func() {
+		iterator := exchange.BuildCandlestickIterator(strings.ToUpper(input.BaseAsset), strings.ToUpper(input.QuoteAsset), input.InitialISO8601)
+		price, err := iterator.GetPriceAt(input.InitialISO8601)
+		result <- priceResult{price, err}
+	}()
+	select {
+	case r := <-result:
+		return r.price, r.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func cancelledOutput(ctx context.Context, input common.SignalCheckInput) common.SignalCheckOutput {
+	return common.SignalCheckOutput{Input: input, IsError: true, ErrorMessage: ctx.Err().Error()}
+}
+
+func summarize(signals []signaltranspiler.SignalTranspilerOutput) BatchSummary {
+	summary := BatchSummary{Total: len(signals)}
+	profitRatioSum := 0.0
+	for _, signal := range signals {
+		switch {
+		case len(signal.Errors) > 0:
+			summary.TranspileErrors++
+			continue
+		case signal.Cancelled:
+			summary.Cancelled++
+			continue
+		case signal.SignalOutput.IsError:
+			summary.CheckErrors++
+			continue
+		}
+		summary.Checked++
+		if signal.SignalOutput.Entered {
+			summary.Entered++
+		}
+		if signal.SignalOutput.HighestTakeProfit > 0 {
+			summary.TookProfit++
+		}
+		if signal.SignalOutput.ReachedStopLoss {
+			summary.StoppedLoss++
+		}
+		profitRatioSum += float64(signal.RealizedProfitRatio)
+	}
+	if summary.Checked > 0 {
+		summary.AverageProfitRatio = common.JsonFloat64(profitRatioSum / float64(summary.Checked))
+	}
+	return summary
+}
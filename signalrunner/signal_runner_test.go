@@ -0,0 +1,59 @@
+package signalrunner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/hts/signaltranspiler"
+	"github.com/marianogappa/signal-checker/common"
+)
+
+func TestRunCancelled(t *testing.T) {
+	var (
+		blocked = make(chan struct{})
+		runner  = NewSignalRunner(2)
+	)
+	defer close(blocked)
+	runner.checkSignal = func(input common.SignalCheckInput) (common.SignalCheckOutput, error) {
+		<-blocked
+		return common.SignalCheckOutput{}, nil
+	}
+	runner.priceAt = func(ctx context.Context, input common.SignalCheckInput) (common.JsonFloat64, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+
+	ts := []struct {
+		name  string
+		input string
+	}{
+		{name: "waiting for the check", input: "BTC/USDT\nENTER: 30000 - 31000\nSTART AT: 2021-06-22T15:21:00Z"},
+		{name: "waiting for the entry price", input: "BTC/USDT\nTP: 5%\nSTART AT: 2021-06-22T15:21:00Z"},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			signals, err := signaltranspiler.NewSignalTranspiler().TranspileAll(tc.input)
+			if err != nil {
+				t.Fatalf("unexpected transpile error: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			done := make(chan BatchOutput, 1)
+			go func() { done <- runner.Run(ctx, signals) }()
+			var result BatchOutput
+			select {
+			case result = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Run didn't return after the context was done")
+			}
+			if !result.Signals[0].Cancelled {
+				t.Errorf("expected the signal to be cancelled, got %+v", result.Signals[0].SignalOutput)
+			}
+			if result.Summary.Cancelled != 1 || result.Summary.CheckErrors != 0 {
+				t.Errorf("expected 1 cancelled signal and no check errors, got %+v", result.Summary)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package signaltranspiler
+
+import (
+	"math"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+// takeProfitRatios turns allocations, the portion of the initial position that each take profit sells (e.g. 0.3 for
+// "32000 (30%)"), into the signal checker's TakeProfitRatios. The checker adds the ratios up, and at each take profit
+// sells that sum's portion of what is left: [0.25, 0.5, 0.25] sells 25% at TP1, 75% of the rest at TP2 and the rest
+// at TP3. The checker requires the ratios to add up to exactly 1, so the last one is whatever is left to get there.
+func takeProfitRatios(allocations []common.JsonFloat64) []common.JsonFloat64 {
+	var (
+		ratios    = []common.JsonFloat64{}
+		remaining = 1.0
+		previous  = 0.0
+		sum       = 0.0
+	)
+	for i, allocation := range allocations {
+		if i == len(allocations)-1 {
+			ratios = append(ratios, common.JsonFloat64(1-sum))
+			break
+		}
+		portion := 1.0
+		if remaining > 0 {
+			portion = math.Min(1, float64(allocation)/remaining)
+		}
+		ratios = append(ratios, common.JsonFloat64(portion-previous))
+		sum += portion - previous
+		previous = portion
+		remaining -= float64(allocation)
+	}
+	return ratios
+}
+
+// takeProfitAllocations is the reverse of takeProfitRatios. It's false if the ratios can't be written as allocations,
+// i.e. if there isn't one per take profit, or if some take profit sells nothing or the last one doesn't sell the rest.
+func takeProfitAllocations(input common.SignalCheckInput) ([]common.JsonFloat64, bool) {
+	if len(input.TakeProfitRatios) == 0 || len(input.TakeProfitRatios) != len(input.TakeProfits) {
+		return nil, false
+	}
+	var (
+		allocations = []common.JsonFloat64{}
+		remaining   = 1.0
+		portion     = 0.0
+	)
+	for _, ratio := range input.TakeProfitRatios {
+		portion += float64(ratio)
+		if portion <= 0 || portion > 1 {
+			return nil, false
+		}
+		allocations = append(allocations, common.JsonFloat64(remaining*portion))
+		remaining -= remaining * portion
+	}
+	if portion != 1 {
+		return nil, false
+	}
+	return allocations, true
+}
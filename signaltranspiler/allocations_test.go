@@ -0,0 +1,127 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/marianogappa/signal-checker/common"
+	"github.com/marianogappa/signal-checker/profitcalculator"
+)
+
+func TestTakeProfitAllocations(t *testing.T) {
+	ts := []struct {
+		name                string
+		takeProfit          string
+		expectedAllocations []common.JsonFloat64
+		expectedRatios      []common.JsonFloat64
+		// expectedProfitRatio is the checker's profit ratio after reaching every take profit
+		expectedProfitRatio float64
+		expectedErr         error
+	}{
+		{
+			name:                "no allocations",
+			takeProfit:          "TAKE PROFIT: 110, 120",
+			expectedProfitRatio: 0.2,
+		},
+		{
+			name:                "two allocations",
+			takeProfit:          "TAKE PROFIT: 110 (30%), 120 (70%)",
+			expectedAllocations: []common.JsonFloat64{0.3, 0.7},
+			expectedRatios:      []common.JsonFloat64{0.3, 0.7},
+			expectedProfitRatio: 0.3*0.1 + 0.7*0.2,
+		},
+		{
+			name:                "a later take profit sells less than an earlier one",
+			takeProfit:          "TAKE PROFIT: 110 (60%), 120 (20%), 130 (20%)",
+			expectedAllocations: []common.JsonFloat64{0.6, 0.2, 0.2},
+			expectedProfitRatio: 0.6*0.1 + 0.2*0.2 + 0.2*0.3,
+		},
+		{
+			name:                "allocations that don't add up to 1 exactly as floats",
+			takeProfit:          "TAKE PROFIT: 110 (6%), 120 (57%), 130 (37%)",
+			expectedAllocations: []common.JsonFloat64{0.06, 0.57, 0.37},
+			expectedProfitRatio: 0.06*0.1 + 0.57*0.2 + 0.37*0.3,
+		},
+		{
+			name:                "allocations over several lines",
+			takeProfit:          "TAKE PROFIT: 110 (25%)\nTAKE PROFIT: 120 (25%), 130 (50%)",
+			expectedAllocations: []common.JsonFloat64{0.25, 0.25, 0.5},
+			expectedProfitRatio: 0.25*0.1 + 0.25*0.2 + 0.5*0.3,
+		},
+		{
+			name:                "numbered take profits on one line",
+			takeProfit:          "TP1 110 (30%), TP2 120 (50%), TP3 130 (20%)",
+			expectedAllocations: []common.JsonFloat64{0.3, 0.5, 0.2},
+			expectedProfitRatio: 0.3*0.1 + 0.5*0.2 + 0.2*0.3,
+		},
+		{
+			name:        "a take profit without allocation",
+			takeProfit:  "TAKE PROFIT: 110 (30%), 120",
+			expectedErr: ErrTakeProfitUnallocated,
+		},
+		{
+			name:        "allocations that don't add up to 100%",
+			takeProfit:  "TAKE PROFIT: 110 (30%), 120 (60%)",
+			expectedErr: ErrTakeProfitAllocationSum,
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := NewSignalTranspiler().Transpile("BTC/USDT\nSTART AT: 2021-06-22T15:21:00Z\nENTER: 99 - 100\n" + tc.takeProfit)
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(output.TakeProfitAllocations, tc.expectedAllocations) {
+				t.Errorf("expected allocations %v, got %v", tc.expectedAllocations, output.TakeProfitAllocations)
+			}
+			ratios := output.SignalInput.TakeProfitRatios
+			if tc.expectedRatios != nil && !reflect.DeepEqual(ratios, tc.expectedRatios) {
+				t.Errorf("expected ratios %v, got %v", tc.expectedRatios, ratios)
+			}
+			// The signal checker rejects ratios whose sum, in this order, isn't exactly 1
+			sum := 0.0
+			for _, ratio := range ratios {
+				sum += float64(ratio)
+			}
+			if len(ratios) > 0 && sum != 1.0 {
+				t.Errorf("expected ratios %v to add up to exactly 1, got %v", ratios, sum)
+			}
+
+			calculator := profitcalculator.NewProfitCalculator(output.SignalInput)
+			calculator.ApplyEvent(common.SignalCheckOutputEvent{EventType: common.ENTERED, Price: 100})
+			profitRatio := 0.0
+			for i, tp := range output.SignalInput.TakeProfits {
+				profitRatio = calculator.ApplyEvent(common.SignalCheckOutputEvent{EventType: common.TAKEN_PROFIT_ + strconv.Itoa(i+1), Price: tp})
+			}
+			if math.Abs(profitRatio-tc.expectedProfitRatio) > 1e-9 {
+				t.Errorf("expected profit ratio %v, got %v", tc.expectedProfitRatio, profitRatio)
+			}
+		})
+	}
+}
+
+func TestNumberedTakeProfitsOnOneLine(t *testing.T) {
+	output, err := NewSignalTranspiler().Transpile("BTC/USDT\nSTART AT: 2021-06-22T15:21:00Z\nENTER: 30000 - 31000\nTP1 32000 (30%), TP2 33000 (50%), TP3 35000 (20%)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []common.JsonFloat64{32000, 33000, 35000}; !reflect.DeepEqual(output.SignalInput.TakeProfits, expected) {
+		t.Errorf("expected take profits %v, got %v", expected, output.SignalInput.TakeProfits)
+	}
+	if expected := []common.JsonFloat64{0.3, 0.5, 0.2}; !reflect.DeepEqual(output.TakeProfitAllocations, expected) {
+		t.Errorf("expected allocations %v, got %v", expected, output.TakeProfitAllocations)
+	}
+	expected := "TAKE PROFIT: 32000 (30%), TAKE PROFIT: 33000 (50%), TAKE PROFIT: 35000 (20%)"
+	if line := joinTokens(output.TokenizedInput[3]); line != expected {
+		t.Errorf("expected the line to read %q, got %q", expected, line)
+	}
+}
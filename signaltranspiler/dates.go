@@ -0,0 +1,266 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	// Time zones like America/Sao_Paulo work even where the system has no time zone database
+	_ "time/tzdata"
+)
+
+// DateParser parses dates in one family of formats, e.g. Unix timestamps. loc is the time zone for dates that don't
+// specify one, and now is the reference for relative dates. It returns false if s is not in the parser's family of
+// formats.
+type DateParser func(s string, loc *time.Location, now time.Time) (ParsedDate, bool)
+
+// ParsedDate is what a DateParser made of a date. A non-nil Warning is reported to the user. If the parser couldn't
+// tell which date s is, e.g. whether 03/04/2021 is in March or April, Date is zero and Warning says why.
+type ParsedDate struct {
+	Date    time.Time
+	Warning error
+}
+
+var ErrAmbiguousDate = errors.New("ambiguous day/month order")
+
+var (
+	rxUnixTimestamp  = regexp.MustCompile(`^(\d{9,10}|\d{12,13})$`)
+	rxNumericDate    = regexp.MustCompile(`^(\d{1,2})[/.](\d{1,2})[/.](\d{4})(?:[\sT,]+(\d{1,2}):(\d{2})(?::(\d{2}))?\s*(AM|PM)?)?$`)
+	rxRelativeDay    = regexp.MustCompile(`^(TODAY|YESTERDAY|TOMORROW)(?:\s+(?:AT\s+)?(\d{1,2}):(\d{2})\s*(AM|PM)?)?$`)
+	rxRelativeAgo    = regexp.MustCompile(`^(\d+)\s+(MINUTE|HOUR|DAY|WEEK)S?\s+AGO$`)
+	rxZoneOffset     = regexp.MustCompile(`^(?:(?:UTC|GMT)\s*)?([+-])(\d{1,2})(?::?(\d{2}))?$`)
+	rxZoneAbbreviate = regexp.MustCompile(`^(Z|UTC|GMT)$`)
+)
+
+// isoLayouts are tried as-is, with the date's own offset if it has one.
+var isoLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// monthNameLayouts are tried after the time zone is split off the end of the date.
+var monthNameLayouts = []string{
+	"Jan 2, 2006 3:04 PM",
+	"Jan 2, 2006 3:04:05 PM",
+	"Jan 2, 2006 15:04",
+	"Jan 2, 2006 15:04:05",
+	"Jan 2, 2006",
+	"January 2, 2006 3:04 PM",
+	"January 2, 2006 15:04",
+	"January 2, 2006",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006",
+	"2 January 2006 15:04",
+	"2 January 2006",
+}
+
+func defaultDateParsers() []DateParser {
+	return []DateParser{
+		parseISODate,
+		parseUnixTimestamp,
+		parseNumericDate,
+		parseMonthNameDate,
+		parseRelativeDate,
+	}
+}
+
+// WithDateParser adds a date parser, which is tried before the built-in ones.
+func WithDateParser(p DateParser) Option {
+	return func(t *SignalTranspiler) {
+		t.dateParsers = append([]DateParser{p}, t.dateParsers...)
+	}
+}
+
+// parseDate tries every date parser in order. Dates without a time zone are in loc, or in UTC if loc is nil.
+func (t SignalTranspiler) parseDate(s string, loc *time.Location) (ParsedDate, error) {
+	parsers := t.dateParsers
+	if parsers == nil {
+		parsers = defaultDateParsers()
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	s = strings.TrimSpace(s)
+	for _, parser := range parsers {
+		if parsed, ok := parser(s, loc, t.currentTime()); ok {
+			return parsed, nil
+		}
+	}
+	return ParsedDate{}, fmt.Errorf("%w [%v]", ErrUnsupportedDateTimeFormat, s)
+}
+
+// parseISODate parses dates like 2021-06-22T15:21:00Z or "2021-06-22 15:21 UTC-3".
+func parseISODate(s string, loc *time.Location, now time.Time) (ParsedDate, bool) {
+	rest, zone, ok := splitZone(s, loc)
+	if !ok {
+		return ParsedDate{}, false
+	}
+	for _, layout := range isoLayouts {
+		if date, err := time.ParseInLocation(layout, strings.ToUpper(rest), zone); err == nil {
+			return ParsedDate{Date: date}, true
+		}
+	}
+	return ParsedDate{}, false
+}
+
+// parseUnixTimestamp parses seconds or milliseconds since the Unix epoch.
+func parseUnixTimestamp(s string, loc *time.Location, now time.Time) (ParsedDate, bool) {
+	if !rxUnixTimestamp.MatchString(s) {
+		return ParsedDate{}, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return ParsedDate{}, false
+	}
+	if len(s) > 10 {
+		return ParsedDate{Date: time.Unix(n/1000, (n%1000)*int64(time.Millisecond)).UTC()}, true
+	}
+	return ParsedDate{Date: time.Unix(n, 0).UTC()}, true
+}
+
+// parseNumericDate parses dates like 22/06/2021 15:21 or 06.22.2021. The order of day and month is decided by
+// whichever can't be a month. If both could, the date is ambiguous, so it's left unread, with a warning.
+func parseNumericDate(s string, loc *time.Location, now time.Time) (ParsedDate, bool) {
+	rest, zone, ok := splitZone(s, loc)
+	if !ok {
+		return ParsedDate{}, false
+	}
+	result := rxNumericDate.FindStringSubmatch(strings.ToUpper(rest))
+	if len(result) == 0 {
+		return ParsedDate{}, false
+	}
+	first, _ := strconv.Atoi(result[1])
+	second, _ := strconv.Atoi(result[2])
+	year, _ := strconv.Atoi(result[3])
+	day, month := first, second
+	switch {
+	case first > 12 && second > 12:
+		return ParsedDate{}, false
+	case second > 12:
+		day, month = second, first
+	}
+	hour, minute, sec, ok := clockTime(result[4], result[5], result[6], result[7])
+	if !ok {
+		return ParsedDate{}, false
+	}
+	if first <= 12 && second <= 12 && first != second {
+		return ParsedDate{Warning: fmt.Errorf("%w in [%v], it could be %04d-%02d-%02d or %04d-%02d-%02d; write it in one of those forms", ErrAmbiguousDate, s, year, second, first, year, first, second)}, true
+	}
+	date := time.Date(year, time.Month(month), day, hour, minute, sec, 0, zone)
+	if date.Day() != day || int(date.Month()) != month {
+		return ParsedDate{}, false
+	}
+	return ParsedDate{Date: date}, true
+}
+
+// parseMonthNameDate parses dates like "Jun 22, 2021 3:21 PM UTC+3" or "22 June 2021".
+func parseMonthNameDate(s string, loc *time.Location, now time.Time) (ParsedDate, bool) {
+	rest, zone, ok := splitZone(s, loc)
+	if !ok {
+		return ParsedDate{}, false
+	}
+	rest = strings.ToUpper(rest)
+	for _, layout := range monthNameLayouts {
+		if date, err := time.ParseInLocation(layout, rest, zone); err == nil {
+			return ParsedDate{Date: date}, true
+		}
+	}
+	return ParsedDate{}, false
+}
+
+// parseRelativeDate parses dates relative to now, like "yesterday 14:00", "today at 9:30 AM" or "3 hours ago".
+func parseRelativeDate(s string, loc *time.Location, now time.Time) (ParsedDate, bool) {
+	rest, zone, ok := splitZone(s, loc)
+	if !ok {
+		return ParsedDate{}, false
+	}
+	rest = strings.ToUpper(rest)
+	now = now.In(zone)
+	if result := rxRelativeAgo.FindStringSubmatch(rest); len(result) > 0 {
+		n, _ := strconv.Atoi(result[1])
+		unit := map[string]time.Duration{"MINUTE": time.Minute, "HOUR": time.Hour, "DAY": 24 * time.Hour, "WEEK": 7 * 24 * time.Hour}[result[2]]
+		return ParsedDate{Date: now.Add(-time.Duration(n) * unit).Truncate(time.Minute)}, true
+	}
+	result := rxRelativeDay.FindStringSubmatch(rest)
+	if len(result) == 0 {
+		return ParsedDate{}, false
+	}
+	days := map[string]int{"YESTERDAY": -1, "TODAY": 0, "TOMORROW": 1}[result[1]]
+	hour, minute, _, ok := clockTime(result[2], result[3], "", result[4])
+	if !ok {
+		return ParsedDate{}, false
+	}
+	return ParsedDate{Date: time.Date(now.Year(), now.Month(), now.Day()+days, hour, minute, 0, 0, zone)}, true
+}
+
+// clockTime converts the captured parts of a time of day, any of which may be empty, into 24-hour clock values.
+func clockTime(hourS, minuteS, secondS, ampm string) (int, int, int, bool) {
+	var hour, minute, second int
+	if hourS != "" {
+		hour, _ = strconv.Atoi(hourS)
+		minute, _ = strconv.Atoi(minuteS)
+	}
+	if secondS != "" {
+		second, _ = strconv.Atoi(secondS)
+	}
+	if ampm != "" {
+		if hour < 1 || hour > 12 {
+			return 0, 0, 0, false
+		}
+		hour %= 12
+		if ampm == "PM" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 || second > 59 {
+		return 0, 0, 0, false
+	}
+	return hour, minute, second, true
+}
+
+// splitZone splits an explicit time zone off the end of a date, e.g. "Z", "UTC", "UTC+3", "GMT-03:00", "+0530" or
+// "America/Sao_Paulo". If there is none, the date is in loc. ok is false if the zone looks explicit but is invalid.
+func splitZone(s string, loc *time.Location) (string, *time.Location, bool) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return s, loc, true
+	}
+	rest := strings.Join(fields[:len(fields)-1], " ")
+	zone, ok, explicit := parseZone(fields[len(fields)-1])
+	if !explicit {
+		return s, loc, true
+	}
+	return rest, zone, ok
+}
+
+// parseZone parses a time zone. explicit is false if s doesn't look like a time zone at all.
+func parseZone(s string) (zone *time.Location, ok bool, explicit bool) {
+	upS := strings.ToUpper(s)
+	if rxZoneAbbreviate.MatchString(upS) {
+		return time.UTC, true, true
+	}
+	if result := rxZoneOffset.FindStringSubmatch(upS); len(result) > 0 {
+		hours, _ := strconv.Atoi(result[2])
+		minutes, _ := strconv.Atoi(result[3])
+		if hours > 14 || minutes > 59 {
+			return nil, false, true
+		}
+		offset := hours*3600 + minutes*60
+		if result[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(upS, offset), true, true
+	}
+	if strings.Contains(s, "/") && !strings.ContainsAny(s, "0123456789") {
+		zone, err := time.LoadLocation(s)
+		return zone, err == nil, true
+	}
+	return nil, false, false
+}
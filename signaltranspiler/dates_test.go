@@ -0,0 +1,75 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+func TestParseDate(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Fatalf("time zone database not available: %v", err)
+	}
+	ts := []struct {
+		name            string
+		date            string
+		loc             *time.Location
+		expected        time.Time
+		expectedWarning error
+		expectedErr     error
+	}{
+		{name: "ISO 8601", date: "2021-06-22T15:21:00Z", expected: time.Date(2021, 6, 22, 15, 21, 0, 0, time.UTC)},
+		{name: "in a time zone", date: "2021-06-22 15:21 America/Sao_Paulo", expected: time.Date(2021, 6, 22, 15, 21, 0, 0, saoPaulo)},
+		{name: "in the signal's time zone", date: "2021-06-22 15:21", loc: saoPaulo, expected: time.Date(2021, 6, 22, 15, 21, 0, 0, saoPaulo)},
+		{name: "Unix timestamp in milliseconds", date: "1624375260000", expected: time.Date(2021, 6, 22, 15, 21, 0, 0, time.UTC)},
+		{name: "day first", date: "22/06/2021 15:21", expected: time.Date(2021, 6, 22, 15, 21, 0, 0, time.UTC)},
+		{name: "month first", date: "06/22/2021 15:21", expected: time.Date(2021, 6, 22, 15, 21, 0, 0, time.UTC)},
+		{name: "same day and month", date: "04/04/2021", expected: time.Date(2021, 4, 4, 0, 0, 0, 0, time.UTC)},
+		{name: "ambiguous day and month", date: "03/04/2021 10:00", expectedWarning: ErrAmbiguousDate},
+		{name: "month name", date: "Jun 22, 2021 3:21 PM UTC+3", expected: time.Date(2021, 6, 22, 15, 21, 0, 0, time.FixedZone("UTC+3", 3*3600))},
+		{name: "relative", date: "yesterday 14:00", expected: time.Date(2021, 6, 21, 14, 0, 0, 0, time.UTC)},
+		{name: "unsupported", date: "the day after tomorrow", expectedErr: ErrUnsupportedDateTimeFormat},
+	}
+	transpiler := NewSignalTranspiler(WithClock(func() time.Time { return time.Date(2021, 6, 22, 18, 0, 0, 0, time.UTC) }))
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			parsed, err := transpiler.parseDate(tc.date, tc.loc)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if !errors.Is(parsed.Warning, tc.expectedWarning) {
+				t.Errorf("expected warning %v, got %v", tc.expectedWarning, parsed.Warning)
+			}
+			if !parsed.Date.Equal(tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, parsed.Date)
+			}
+		})
+	}
+}
+
+func TestTimezoneDirective(t *testing.T) {
+	ts := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "above START AT", input: "TIMEZONE: America/Sao_Paulo\nSTART AT: 2021-06-22 15:21", expected: "2021-06-22T18:21:00Z"},
+		{name: "below START AT", input: "START AT: 2021-06-22 15:21\nTIMEZONE: America/Sao_Paulo", expected: "2021-06-22T18:21:00Z"},
+		{name: "below START AT, with an explicit zone", input: "START AT: 2021-06-22T15:21:00Z\nTZ: America/Sao_Paulo", expected: "2021-06-22T15:21:00Z"},
+		{name: "no directive", input: "START AT: 2021-06-22 15:21", expected: "2021-06-22T15:21:00Z"},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := NewSignalTranspiler().Transpile("BTC/USDT\nENTER: 30000 - 31000\n" + tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.SignalInput.InitialISO8601 != common.ISO8601(tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, output.SignalInput.InitialISO8601)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	SEVERITY_ERROR   = "error"
+	SEVERITY_WARNING = "warning"
+)
+
+// Diagnostic is a structured error or warning. Line is the zero-based line in the input (-1 if the diagnostic is
+// about the signal as a whole), and [ColumnStart, ColumnEnd) is the affected span within that line, in characters.
+// SuggestedFix, if present, is a replacement for the whole line.
+type Diagnostic struct {
+	Code         string `json:"code"`
+	Severity     string `json:"severity"`
+	Line         int    `json:"line"`
+	ColumnStart  int    `json:"columnStart"`
+	ColumnEnd    int    `json:"columnEnd"`
+	Message      string `json:"message"`
+	SuggestedFix string `json:"suggestedFix,omitempty"`
+}
+
+var diagnosticCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrUnrecognizedInstruction, "unrecognized_instruction"},
+	{ErrMarketAlreadySupplied, "market_already_supplied"},
+	{ErrEnterRangeAlreadySupplied, "enter_range_already_supplied"},
+	{ErrInvalidateAfterDaysAlreadySupplied, "timeout_already_supplied"},
+	{ErrIsShortAlreadySupplied, "direction_already_supplied"},
+	{ErrInitialISO8601AlreadySupplied, "start_at_already_supplied"},
+	{ErrExchangeAlreadySupplied, "exchange_already_supplied"},
+	{ErrStopLossAlreadySupplied, "stop_loss_already_supplied"},
+	{ErrTrailingStopAlreadySupplied, "trailing_stop_already_supplied"},
+	{ErrBreakEvenAlreadySupplied, "break_even_already_supplied"},
+	{ErrInvalidTrailingStop, "invalid_trailing_stop"},
+	{ErrLeverageAlreadySupplied, "leverage_already_supplied"},
+	{ErrMarginModeAlreadySupplied, "margin_mode_already_supplied"},
+	{ErrInvalidLeverage, "invalid_leverage"},
+	{ErrMaximumTimeout, "maximum_timeout"},
+	{ErrInvalidTimeout, "invalid_timeout"},
+	{ErrInvalidateBeforeStart, "invalidate_before_start"},
+	{ErrMalformedInteger, "malformed_integer"},
+	{ErrMalformedFloat, "malformed_float"},
+	{ErrInvalidEnterRange, "invalid_enter_range"},
+	{ErrInvalidEnterAt, "invalid_enter_at"},
+	{ErrUnsupportedExchange, "unsupported_exchange"},
+	{ErrUnsupportedDateTimeFormat, "unsupported_datetime_format"},
+	{ErrMarketRequired, "market_required"},
+	{ErrEnterRangeRequired, "enter_range_required"},
+	{ErrInitialISO8601Required, "start_at_required"},
+	{ErrExchangeRequired, "exchange_required"},
+	{ErrDirectionRequired, "direction_required"},
+	{ErrTimeoutRequired, "timeout_required"},
+	{ErrMixesSeparators, "mixes_separators"},
+	{ErrTimezoneAlreadySupplied, "timezone_already_supplied"},
+	{ErrUnsupportedTimezone, "unsupported_timezone"},
+	{ErrUnsupportedDialect, "unsupported_dialect"},
+	{ErrExtractedField, "extracted_field"},
+	{ErrAmbiguousDate, "ambiguous_date"},
+	{ErrTakeProfitBehindEntry, "take_profit_behind_entry"},
+	{ErrStopLossWrongSide, "stop_loss_wrong_side"},
+	{ErrStopLossBeyondTakeProfit, "stop_loss_beyond_take_profit"},
+	{ErrTakeProfitUnallocated, "take_profit_unallocated"},
+	{ErrTakeProfitAllocationSum, "take_profit_allocation_sum"},
+	{ErrBreakEvenAfterNoTakeProfit, "break_even_after_no_take_profit"},
+	{ErrLeverageRequiresFutures, "leverage_requires_futures"},
+	{ErrUnsortedTakeProfits, WARNING_UNSORTED_TAKE_PROFITS},
+	{ErrExtremeRiskReward, WARNING_EXTREME_RISK_REWARD},
+	{ErrFutureStart, WARNING_FUTURE_START},
+	{ErrFarStopLoss, WARNING_FAR_STOP_LOSS},
+	{ErrStopLossBeyondLiquidation, WARNING_STOP_LOSS_BEYOND_LIQUIDATION},
+	{ErrUnlistedMarket, WARNING_UNLISTED_MARKET},
+}
+
+func diagnosticCode(err error) string {
+	for _, dc := range diagnosticCodes {
+		if errors.Is(err, dc.err) {
+			return dc.code
+		}
+	}
+	return "unknown"
+}
+
+// addError records an error both as a string and as a diagnostic. si is the instruction the error is about, or nil if
+// it is about the signal as a whole.
+func (o *SignalTranspilerOutput) addError(err error, si *signalInstruction) {
+	o.Errors = append(o.Errors, err.Error())
+	o.errs = append(o.errs, err)
+	o.Diagnostics = append(o.Diagnostics, newDiagnostic(err, SEVERITY_ERROR, si))
+}
+
+// addWarning is like addError, for warnings.
+func (o *SignalTranspilerOutput) addWarning(err error, si *signalInstruction) {
+	o.Warnings = append(o.Warnings, err.Error())
+	o.Diagnostics = append(o.Diagnostics, newDiagnostic(err, SEVERITY_WARNING, si))
+}
+
+func newDiagnostic(err error, severity string, si *signalInstruction) Diagnostic {
+	diagnostic := Diagnostic{
+		Code:         diagnosticCode(err),
+		Severity:     severity,
+		Line:         -1,
+		Message:      err.Error(),
+		SuggestedFix: suggestFix(err, si),
+	}
+	if si != nil && !si.isInferred {
+		diagnostic.Line = si.lineNumber
+		diagnostic.ColumnStart, diagnostic.ColumnEnd = diagnosticSpan(si)
+	}
+	return diagnostic
+}
+
+// diagnosticSpan finds the span of the first error token in the instruction's raw input, falling back to the whole
+// line without surrounding whitespace.
+func diagnosticSpan(si *signalInstruction) (int, int) {
+	if len(si.span) == 2 {
+		return si.span[0], si.span[1]
+	}
+	if si.sourceLine != "" {
+		// The instruction was rewritten by a dialect, so its tokens can't be found in the line
+		return trimmedSpan(si.sourceLine)
+	}
+	upRawInput := strings.ToUpper(si.rawInput)
+	for _, token := range si.tokenizedInput {
+		if token.TokenType != TOKEN_ERROR || strings.TrimSpace(token.Input) == "" {
+			continue
+		}
+		if i := strings.Index(upRawInput, strings.ToUpper(token.Input)); i != -1 {
+			start := utf8.RuneCountInString(si.rawInput[:i])
+			return start, start + utf8.RuneCountInString(token.Input)
+		}
+	}
+	return trimmedSpan(si.rawInput)
+}
+
+func trimmedSpan(line string) (int, int) {
+	trimmed := strings.TrimSpace(line)
+	start := utf8.RuneCountInString(line[:strings.Index(line, trimmed)])
+	return start, start + utf8.RuneCountInString(trimmed)
+}
+
+func suggestFix(err error, si *signalInstruction) string {
+	switch {
+	case errors.Is(err, ErrMarketRequired):
+		return "MARKET: BTC/USDT"
+	case errors.Is(err, ErrInitialISO8601Required):
+		return "START AT: 2021-06-22T15:21:03Z"
+	case errors.Is(err, ErrEnterRangeRequired):
+		return "ENTER: IMMEDIATELY"
+	case errors.Is(err, ErrExchangeRequired):
+		return "EXCHANGE: BINANCE"
+	case errors.Is(err, ErrDirectionRequired):
+		return "LONG"
+	case errors.Is(err, ErrTimeoutRequired):
+		return "TIMEOUT AFTER: 2 DAYS"
+	case errors.Is(err, ErrUnsupportedExchange):
+		return "EXCHANGE: BINANCE"
+	case errors.Is(err, ErrUnsupportedTimezone):
+		return "TIMEZONE: UTC"
+	case si == nil:
+		return ""
+	case errors.Is(err, ErrUnlistedMarket) && si.name == instrNameMarket:
+		var unlisted *UnlistedMarketError
+		if errors.As(err, &unlisted) && unlisted.Suggestion != "" {
+			return "MARKET: " + unlisted.Suggestion
+		}
+	case errors.Is(err, ErrInvalidEnterRange) && len(si.tokenizedInput) == 5:
+		return "ENTER BETWEEN: " + si.tokenizedInput[4].Input + " - " + si.tokenizedInput[2].Input
+	case errors.Is(err, ErrMarketAlreadySupplied), errors.Is(err, ErrEnterRangeAlreadySupplied),
+		errors.Is(err, ErrInvalidateAfterDaysAlreadySupplied), errors.Is(err, ErrIsShortAlreadySupplied),
+		errors.Is(err, ErrInitialISO8601AlreadySupplied), errors.Is(err, ErrExchangeAlreadySupplied),
+		errors.Is(err, ErrStopLossAlreadySupplied), errors.Is(err, ErrTimezoneAlreadySupplied),
+		errors.Is(err, ErrTrailingStopAlreadySupplied), errors.Is(err, ErrBreakEvenAlreadySupplied),
+		errors.Is(err, ErrLeverageAlreadySupplied), errors.Is(err, ErrMarginModeAlreadySupplied),
+		errors.Is(err, ErrUnrecognizedInstruction):
+		return "// " + strings.TrimSpace(si.rawInput)
+	}
+	return ""
+}
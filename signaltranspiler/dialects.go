@@ -0,0 +1,363 @@
+package signaltranspiler
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+// Dialect rewrites signals written in a signal channel's own style into the built-in instructions.
+type Dialect struct {
+	Name string
+	// Normalize returns the instructions that each line stands for. Lines that are blank, comments, separators or
+	// directives are passed as "" and their result is ignored. A line may stand for several instructions, e.g.
+	// "#BTC/USDT LONG Entry: 30000-31000", or for none, e.g. the values listed under a heading like "Targets:", which
+	// are returned on the heading's line instead.
+	Normalize func(lines []string) [][]string
+}
+
+var (
+	// DialectTelegram reads the one-line or few-line signals common in Telegram channels, with emojis, hashtags,
+	// "Entry"/"Entry zone", "Targets" with numbered values and "Stop".
+	DialectTelegram = Dialect{
+		Name:      "telegram",
+		Normalize: normalizeTelegram,
+	}
+	// DialectCornix reads signals in the format of the Cornix trading bot, where entries, take profits and stops are
+	// listed under headings, one per line.
+	DialectCornix = Dialect{
+		Name:      "cornix",
+		Normalize: normalizeCornix,
+	}
+
+	dialects = []Dialect{DialectTelegram, DialectCornix}
+)
+
+// LookupDialect returns the preset dialect with the given name, e.g. "telegram".
+func LookupDialect(name string) (Dialect, bool) {
+	for _, d := range dialects {
+		if strings.EqualFold(d.Name, name) {
+			return d, true
+		}
+	}
+	return Dialect{}, false
+}
+
+// DialectNames returns the names of the preset dialects.
+func DialectNames() []string {
+	names := []string{}
+	for _, d := range dialects {
+		names = append(names, d.Name)
+	}
+	return names
+}
+
+// WithDialect reads every signal in the given dialect, unless the signal has a DIALECT directive.
+func WithDialect(d Dialect) Option {
+	return func(t *SignalTranspiler) {
+		t.dialect = &d
+	}
+}
+
+var (
+	rxDialect          = regexp.MustCompile(`^\s*DIALECT:?\s*(\S+)\s*(//.*)?$`)
+	rxKeycap           = regexp.MustCompile(`[0-9#*]\x{FE0F}?\x{20E3}`)
+	rxEnumerator       = regexp.MustCompile(`(^|\s)\d{1,2}\s*[)\]:]|(^|\s)\d{1,2}\.\s`)
+	rxAllocation       = regexp.MustCompile(`-\s*[\d.]+\s*%`)
+	rxDialectAllocated = regexp.MustCompile(`(\d+(?:\.\d+)?%?)(?:\s*\(\s*(\d+(?:\.\d+)?)\s*%\s*\)|\s*-\s*(\d+(?:\.\d+)?)\s*%)?`)
+	rxDialectValue     = regexp.MustCompile(`\d+(\.\d+)?\s*%?`)
+	rxDialectPair      = regexp.MustCompile(`\b([A-Z0-9]{2,10})\s*[/_-]\s*([A-Z]{2,6})\b`)
+	rxDialectKeyword   = regexp.MustCompile(`\b(ENTRY ZONE|ENTRY PRICE|ENTRY RANGE|ENTRY|BUY ZONE|BUY RANGE|ENTER|TARGETS?|TAKE[- ]?PROFITS?|TPS?\d*|STOP[- ]?LOSS|STOP|SL|LONG|SHORT|LEVERAGE|EXCHANGE)\b`)
+	rxDialectImmediate = regexp.MustCompile(`\b(NOW|MARKET|CMP|IMMEDIATELY)\b`)
+	rxCornixHeading    = regexp.MustCompile(`^(ENTRY TARGETS|ENTRY ZONE|ENTRY|TAKE[- ]?PROFIT TARGETS|TARGETS|STOP TARGETS|STOP LOSS|STOP)\s*:?\s*$`)
+	rxCornixExchange   = regexp.MustCompile(`^EXCHANGES?\s*:?\s*([^,]+)`)
+	rxCornixSignalType = regexp.MustCompile(`^SIGNAL TYPE\s*:?.*\b(LONG|SHORT)\b`)
+)
+
+// dialectFor returns the dialect of a signal: the one in its DIALECT directive, or else the transpiler's.
+func (t SignalTranspiler) dialectFor(lines []string) *Dialect {
+	for _, line := range lines {
+		if result := rxDialect.FindStringSubmatch(strings.ToUpper(line)); len(result) > 0 {
+			if d, ok := LookupDialect(result[1]); ok {
+				return &d
+			}
+		}
+	}
+	return t.dialect
+}
+
+// newSignalInstructions makes the signal instructions for the given lines, rewritten by the dialect if there is one.
+func newSignalInstructions(lines []string, firstLine int, dialect *Dialect) []*signalInstruction {
+	signalInstructions := []*signalInstruction{}
+	if dialect == nil {
+		for i, line := range lines {
+			signalInstructions = append(signalInstructions, newSignalInstruction(line, firstLine+i, false))
+		}
+		return signalInstructions
+	}
+	codes := make([]string, len(lines))
+	for i, line := range lines {
+		if !isDialectNeutral(line) {
+			codes[i] = strings.TrimSpace(strings.Split(line, "//")[0])
+		}
+	}
+	normalized := dialect.Normalize(codes)
+	for i, line := range lines {
+		if codes[i] == "" || (len(normalized[i]) == 1 && normalized[i][0] == codes[i]) {
+			signalInstructions = append(signalInstructions, newSignalInstruction(line, firstLine+i, false))
+			continue
+		}
+		if len(normalized[i]) == 0 {
+			// The line's values were taken by an earlier line, e.g. the heading of its list.
+			signalInstructions = append(signalInstructions, &signalInstruction{
+				rawInput:       line,
+				name:           instrNameEmpty,
+				lineNumber:     firstLine + i,
+				tokenizedInput: []InputToken{{Input: line, TokenType: TOKEN_COMMENT}},
+				isApplied:      true,
+			})
+			continue
+		}
+		for j, segment := range normalized[i] {
+			if comment := trailingComment(line); comment != "" && j == len(normalized[i])-1 {
+				segment += " " + comment
+			}
+			si := newSignalInstruction(segment, firstLine+i, false)
+			si.sourceLine = line
+			signalInstructions = append(signalInstructions, si)
+		}
+	}
+	return signalInstructions
+}
+
+// isDialectNeutral is true for lines that read the same in every dialect.
+func isDialectNeutral(line string) bool {
+	upLine := strings.ToUpper(line)
+	return rxEmpty.MatchString(upLine) || rxSeparator.MatchString(upLine) || rxDialect.MatchString(upLine) ||
+		rxStrict.MatchString(upLine) || rxTimezone.MatchString(line)
+}
+
+func normalizeTelegram(lines []string) [][]string {
+	normalized := make([][]string, len(lines))
+	for i, line := range lines {
+		normalized[i] = normalizeTelegramLine(line)
+	}
+	return normalized
+}
+
+// normalizeTelegramLine splits a line at every keyword, like "Entry" or "Stop", and rewrites each part.
+func normalizeTelegramLine(line string) []string {
+	upLine := stripDecorations(line)
+	if upLine == "" {
+		return []string{}
+	}
+	matches := rxDialectKeyword.FindAllStringIndex(upLine, -1)
+	segments := []string{}
+	if len(matches) == 0 || matches[0][0] > 0 {
+		end := len(upLine)
+		if len(matches) > 0 {
+			end = matches[0][0]
+		}
+		if prefix := normalizeDialectPrefix(upLine[:end]); prefix != "" {
+			segments = append(segments, prefix)
+		}
+	}
+	previousKind := ""
+	for i, match := range matches {
+		end := len(upLine)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		keyword := upLine[match[0]:match[1]]
+		rest := strings.Trim(upLine[match[1]:end], " :=-")
+		kind := dialectKeywordKind(keyword)
+		// Numbered take profits like "TP1 32000 TP2 33000" make a single instruction
+		if kind == instrNameTakeProfit && previousKind == kind {
+			values := dialectTakeProfits(rest)
+			if len(values) > 0 && strings.HasPrefix(segments[len(segments)-1], "TAKE PROFIT: ") {
+				segments[len(segments)-1] += ", " + strings.Join(values, ", ")
+				continue
+			}
+		}
+		segments = append(segments, normalizeDialectSegment(kind, keyword, rest))
+		previousKind = kind
+	}
+	return segments
+}
+
+// stripDecorations uppercases a line and drops emojis, hashtag and cashtag signs and repeated spaces.
+func stripDecorations(line string) string {
+	line = rxKeycap.ReplaceAllString(line, " ")
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(line) {
+		switch {
+		case r == '#' || r == '$':
+			continue
+		case r == '•' || r == '|' || unicode.In(r, unicode.So, unicode.Sk, unicode.Mn, unicode.Me, unicode.Cf, unicode.Cs):
+			sb.WriteRune(' ')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func dialectKeywordKind(keyword string) string {
+	switch {
+	case strings.HasPrefix(keyword, "ENT"), strings.HasPrefix(keyword, "BUY"):
+		return instrNameEnter
+	case strings.HasPrefix(keyword, "TAKE"), strings.HasPrefix(keyword, "TARGET"), strings.HasPrefix(keyword, "TP"):
+		return instrNameTakeProfit
+	case strings.HasPrefix(keyword, "STOP"), keyword == "SL":
+		return instrNameStopLoss
+	case keyword == "LONG", keyword == "SHORT":
+		return instrNameDirection
+	case keyword == "EXCHANGE":
+		return instrNameExchange
+	}
+	return keyword
+}
+
+// normalizeDialectSegment rewrites a keyword and the text after it as an instruction. If it can't, it returns the
+// text as it is, so that it's reported as unrecognized.
+func normalizeDialectSegment(kind, keyword, rest string) string {
+	original := strings.TrimSpace(keyword + " " + rest)
+	switch kind {
+	case instrNameEnter:
+		if rxDialectImmediate.MatchString(rest) {
+			return "ENTER: IMMEDIATELY"
+		}
+		if enter := dialectEnterRange(dialectValues(rest)); enter != "" {
+			return enter
+		}
+	case instrNameTakeProfit:
+		if values := dialectTakeProfits(rest); len(values) > 0 {
+			return "TAKE PROFIT: " + strings.Join(values, ", ")
+		}
+	case instrNameStopLoss:
+		if values := dialectValues(rest); len(values) > 0 {
+			return "STOP LOSS: " + values[0]
+		}
+	case instrNameDirection:
+		return keyword
+	case instrNameExchange:
+		return "EXCHANGE: " + dialectExchange(rest)
+	case instrNameLeverage:
+		return "LEVERAGE: " + strings.TrimLeft(rest, " :")
+	}
+	return original
+}
+
+// normalizeDialectPrefix rewrites the text before the first keyword, which is usually the market.
+func normalizeDialectPrefix(prefix string) string {
+	prefix = strings.TrimSpace(prefix)
+	if result := rxDialectPair.FindStringSubmatch(prefix); len(result) > 0 {
+		return "MARKET: " + result[1] + "/" + result[2]
+	}
+	if exchange := dialectExchange(prefix); isSInSS(exchange, exchangeList) {
+		return "EXCHANGE: " + exchange
+	}
+	return prefix
+}
+
+// dialectValues returns the numbers in s, with their "%" if they have one, without list numbering like "1)".
+func dialectValues(s string) []string {
+	s = rxEnumerator.ReplaceAllString(s, " ")
+	values := []string{}
+	for _, value := range rxDialectValue.FindAllString(s, -1) {
+		values = append(values, strings.Replace(value, " ", "", -1))
+	}
+	return values
+}
+
+// dialectTakeProfits is like dialectValues, but keeps allocations like "32000 (30%)" or "32000 - 30%" as
+// "32000 (30%)".
+func dialectTakeProfits(s string) []string {
+	s = rxEnumerator.ReplaceAllString(s, " ")
+	values := []string{}
+	for _, match := range rxDialectAllocated.FindAllStringSubmatch(s, -1) {
+		switch {
+		case match[2] != "":
+			values = append(values, match[1]+" ("+match[2]+"%)")
+		case match[3] != "":
+			values = append(values, match[1]+" ("+match[3]+"%)")
+		default:
+			values = append(values, match[1])
+		}
+	}
+	return values
+}
+
+// dialectEnterRange makes an enter range out of the lowest and highest prices.
+func dialectEnterRange(values []string) string {
+	prices := []float64{}
+	for _, value := range values {
+		if price, err := strconv.ParseFloat(value, 64); err == nil {
+			prices = append(prices, price)
+		}
+	}
+	if len(prices) == 0 {
+		return ""
+	}
+	sort.Float64s(prices)
+	return "ENTER BETWEEN: " + renderFloat(common.JsonFloat64(prices[0])) + " - " + renderFloat(common.JsonFloat64(prices[len(prices)-1]))
+}
+
+func dialectExchange(s string) string {
+	exchange := strings.Replace(strings.TrimSpace(s), " ", "", -1)
+	if exchange == "BINANCEFUTURES" {
+		return "BINANCEUSDMFUTURES"
+	}
+	return exchange
+}
+
+// normalizeCornix reads one instruction per line, except for headings like "Take-Profit Targets:", whose values are
+// on the lines that follow them.
+func normalizeCornix(lines []string) [][]string {
+	var (
+		normalized = make([][]string, len(lines))
+		heading    = -1
+		kind       string
+		values     []string
+	)
+	closeHeading := func() {
+		if heading == -1 {
+			return
+		}
+		normalized[heading] = []string{normalizeDialectSegment(kind, stripDecorations(lines[heading]), strings.Join(values, " "))}
+		heading = -1
+	}
+	for i, line := range lines {
+		upLine := stripDecorations(line)
+		if heading != -1 && upLine != "" && unicode.IsDigit(rune(upLine[0])) {
+			// Allocations like "1) 32000 - 50%" are only kept for take profits
+			lineValues := dialectValues(rxAllocation.ReplaceAllString(upLine, ""))
+			if kind == instrNameTakeProfit {
+				lineValues = dialectTakeProfits(upLine)
+			}
+			if kind != instrNameEnter && len(lineValues) > 1 {
+				lineValues = lineValues[:1]
+			}
+			values = append(values, lineValues...)
+			normalized[i] = []string{}
+			continue
+		}
+		closeHeading()
+		switch {
+		case rxCornixHeading.MatchString(upLine):
+			heading, kind, values = i, dialectKeywordKind(rxCornixHeading.FindStringSubmatch(upLine)[1]), []string{}
+		case rxCornixExchange.MatchString(upLine):
+			normalized[i] = []string{"EXCHANGE: " + dialectExchange(rxCornixExchange.FindStringSubmatch(upLine)[1])}
+		case rxCornixSignalType.MatchString(upLine):
+			normalized[i] = []string{rxCornixSignalType.FindStringSubmatch(upLine)[1]}
+		default:
+			normalized[i] = normalizeTelegramLine(line)
+		}
+	}
+	closeHeading()
+	return normalized
+}
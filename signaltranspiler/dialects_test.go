@@ -0,0 +1,64 @@
+package signaltranspiler
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected outputs in testdata")
+
+// TestDialectFixtures transpiles every real-world message in testdata/dialects/<dialect>/*.txt in its dialect, and
+// checks the result against the SignalCheckInput in the .json file next to it.
+func TestDialectFixtures(t *testing.T) {
+	for _, name := range DialectNames() {
+		dialect, _ := LookupDialect(name)
+		paths, err := filepath.Glob(filepath.Join("testdata", "dialects", name, "*.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(paths) == 0 {
+			t.Errorf("no fixtures for dialect %v", name)
+		}
+		for _, path := range paths {
+			t.Run(name+"/"+filepath.Base(path), func(t *testing.T) {
+				message, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Messages don't say when they were posted, which is what START AT is
+				input := strings.TrimRight(string(message), "\n") + "\nSTART AT: 2021-06-22T15:21:00Z"
+				output, err := NewSignalTranspiler(WithDialect(dialect)).Transpile(input)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				expectedPath := strings.TrimSuffix(path, ".txt") + ".json"
+				if *update {
+					bs, _ := json.MarshalIndent(output.SignalInput, "", "  ")
+					if err := ioutil.WriteFile(expectedPath, append(bs, '\n'), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				bs, err := ioutil.ReadFile(expectedPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var expected common.SignalCheckInput
+				if err := json.Unmarshal(bs, &expected); err != nil {
+					t.Fatalf("%v: %v", expectedPath, err)
+				}
+				if !reflect.DeepEqual(output.SignalInput, expected) {
+					actual, _ := json.MarshalIndent(output.SignalInput, "", "  ")
+					t.Errorf("expected\n%s\ngot\n%s", bs, actual)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"strings"
+)
+
+// TranspileError is returned by Transpile when there were errors transpiling. It wraps every error that was found, so
+// errors.Is and errors.As match against any of them, e.g. errors.Is(err, ErrMarketRequired).
+type TranspileError struct {
+	Errs []error
+}
+
+func (e *TranspileError) Error() string {
+	msgs := []string{}
+	for _, err := range e.Errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *TranspileError) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *TranspileError) As(target interface{}) bool {
+	for _, err := range e.Errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap returns the wrapped errors.
+func (e *TranspileError) Unwrap() []error {
+	return e.Errs
+}
@@ -0,0 +1,113 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestTranspileErrorIs(t *testing.T) {
+	const (
+		market = "BTC/USDT\n"
+		enter  = "ENTER: 30000 - 31000\n"
+		start  = "START AT: 2021-06-22T15:21:00Z\n"
+		signal = market + enter + start
+	)
+	ts := []struct {
+		input       string
+		expectedErr error
+	}{
+		{input: signal + "FOO", expectedErr: ErrUnrecognizedInstruction},
+		{input: signal + "MARKET: ETH/USDT", expectedErr: ErrMarketAlreadySupplied},
+		{input: signal + "ENTER: 1 - 2", expectedErr: ErrEnterRangeAlreadySupplied},
+		{input: signal + "TIMEOUT IN 2 DAYS\nTIMEOUT IN 3 DAYS", expectedErr: ErrInvalidateAfterDaysAlreadySupplied},
+		{input: signal + "SHORT\nLONG", expectedErr: ErrIsShortAlreadySupplied},
+		{input: signal + "START AT: 2021-06-23T15:21:00Z", expectedErr: ErrInitialISO8601AlreadySupplied},
+		{input: signal + "EXCHANGE: BINANCE\nEXCHANGE: BINANCE", expectedErr: ErrExchangeAlreadySupplied},
+		{input: signal + "SL: 29000\nSL: 28000", expectedErr: ErrStopLossAlreadySupplied},
+		{input: signal + "TRAILING STOP: 2%\nTRAILING STOP: 3%", expectedErr: ErrTrailingStopAlreadySupplied},
+		{input: signal + "TP: 32000, 33000\nBREAK EVEN AFTER TP1\nBREAK EVEN AFTER TP2", expectedErr: ErrBreakEvenAlreadySupplied},
+		{input: signal + "TRAILING STOP: 0%", expectedErr: ErrInvalidTrailingStop},
+		{input: signal + "LEVERAGE: 10X\nLEVERAGE: 20X", expectedErr: ErrLeverageAlreadySupplied},
+		{input: signal + "CROSS\nISOLATED", expectedErr: ErrMarginModeAlreadySupplied},
+		{input: signal + "LEVERAGE: 0X", expectedErr: ErrInvalidLeverage},
+		{input: signal + "TIMEOUT IN 5 WEEKS", expectedErr: ErrMaximumTimeout},
+		{input: signal + "TIMEOUT IN 0 DAYS", expectedErr: ErrInvalidTimeout},
+		{input: signal + "INVALIDATE AT: 2021-06-21T00:00:00Z", expectedErr: ErrInvalidateBeforeStart},
+		{input: signal + "TIMEOUT IN 99999999999999999999 DAYS", expectedErr: ErrMalformedInteger},
+		{input: market + start + "ENTER: 1.2.3 - 4", expectedErr: ErrMalformedFloat},
+		{input: market + start + "ENTER: 31000 - 30000", expectedErr: ErrInvalidEnterRange},
+		{input: market + start + "ENTER: 30000", expectedErr: ErrInvalidEnterAt},
+		{input: market + start + "ENTER: 30000, 30500 - 31000", expectedErr: ErrMixesSeparators},
+		{input: signal + "EXCHANGE: FOOBAR", expectedErr: ErrUnsupportedExchange},
+		{input: market + enter + "START AT: 2021-99-99", expectedErr: ErrUnsupportedDateTimeFormat},
+		{input: enter + start, expectedErr: ErrMarketRequired},
+		{input: "STRICT\n" + market + start, expectedErr: ErrEnterRangeRequired},
+		{input: "STRICT\n" + market + enter, expectedErr: ErrInitialISO8601Required},
+		{input: "STRICT\n" + signal, expectedErr: ErrExchangeRequired},
+		{input: "STRICT\n" + signal, expectedErr: ErrDirectionRequired},
+		{input: "STRICT\n" + signal, expectedErr: ErrTimeoutRequired},
+		{input: "TIMEZONE: UTC\nTIMEZONE: UTC\n" + signal, expectedErr: ErrTimezoneAlreadySupplied},
+		{input: "TIMEZONE: Mars/Olympus\n" + signal, expectedErr: ErrUnsupportedTimezone},
+		{input: "DIALECT: FOO\n" + signal, expectedErr: ErrUnsupportedDialect},
+		{input: signal + "TP: 29000", expectedErr: ErrTakeProfitBehindEntry},
+		{input: signal + "SL: 32000", expectedErr: ErrStopLossWrongSide},
+		{input: signal + "SHORT\nTP: 29000\nSL: 28000", expectedErr: ErrStopLossBeyondTakeProfit},
+		{input: signal + "TP: 32000 (50%), 33000", expectedErr: ErrTakeProfitUnallocated},
+		{input: signal + "TP: 32000 (50%), 33000 (60%)", expectedErr: ErrTakeProfitAllocationSum},
+		{input: signal + "TP: 32000\nBREAK EVEN AFTER TP2", expectedErr: ErrBreakEvenAfterNoTakeProfit},
+		{input: signal + "EXCHANGE: BINANCE\nLEVERAGE: 10X", expectedErr: ErrLeverageRequiresFutures},
+	}
+	for _, tc := range ts {
+		t.Run(tc.expectedErr.Error(), func(t *testing.T) {
+			_, err := NewSignalTranspiler().Transpile(tc.input)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			var transpileErr *TranspileError
+			if !errors.As(err, &transpileErr) {
+				t.Fatalf("expected a *TranspileError, got %T", err)
+			}
+		})
+	}
+}
+
+// TestTranspileErrorEverySentinel checks that every sentinel with a diagnostic code matches through a TranspileError,
+// however it's wrapped and wherever it is among the errors, and that no other sentinel does.
+func TestTranspileErrorEverySentinel(t *testing.T) {
+	for i, dc := range diagnosticCodes {
+		t.Run(dc.code, func(t *testing.T) {
+			other := diagnosticCodes[(i+1)%len(diagnosticCodes)].err
+			err := error(&TranspileError{Errs: []error{
+				fmt.Errorf("%w [x]", other),
+				fmt.Errorf("%w: detail", dc.err),
+			}})
+			if !errors.Is(err, dc.err) {
+				t.Errorf("expected error to match %v", dc.err)
+			}
+			if !errors.Is(fmt.Errorf("wrapped: %w", err), dc.err) {
+				t.Errorf("expected wrapped error to match %v", dc.err)
+			}
+			for _, unrelated := range diagnosticCodes {
+				if unrelated.err != dc.err && unrelated.err != other && errors.Is(err, unrelated.err) {
+					t.Errorf("expected error not to match %v", unrelated.err)
+				}
+			}
+		})
+	}
+}
+
+func TestTranspileErrorAs(t *testing.T) {
+	unlisted := &UnlistedMarketError{Market: "RVN/USDT", Exchange: "BINANCE"}
+	err := error(&TranspileError{Errs: []error{
+		fmt.Errorf("%w [FOO]", ErrUnrecognizedInstruction),
+		unlisted,
+	}})
+	var actual *UnlistedMarketError
+	if !errors.As(err, &actual) || actual != unlisted {
+		t.Fatalf("expected errors.As to find %v, got %v", unlisted, actual)
+	}
+	if !errors.Is(err, ErrUnlistedMarket) {
+		t.Errorf("expected error to match %v", ErrUnlistedMarket)
+	}
+}
@@ -0,0 +1,78 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+const exitsTestSignal = "BTC/USDT\nSTART AT: 2021-06-22T15:21:00Z\nENTER: 30000 - 31000\nTP: 32000, 33000\n"
+
+func TestTrailingStop(t *testing.T) {
+	ts := []struct {
+		line         string
+		expected     common.JsonFloat64
+		expectedLine string
+		expectedErr  error
+	}{
+		{line: "TRAILING STOP: 2%", expected: 2, expectedLine: "TRAILING STOP: 2%"},
+		{line: "trailing stop loss 1.5 %", expected: 1.5, expectedLine: "TRAILING STOP: 1.5%"},
+		{line: "TRAILING SL: 3%", expected: 3, expectedLine: "TRAILING STOP: 3%"},
+		{line: "TRAIL 2% // tight", expected: 2, expectedLine: "TRAILING STOP: 2% // tight"},
+		{line: "TRAILING STOP: 0%", expectedErr: ErrInvalidTrailingStop},
+		{line: "TRAILING STOP: 100%", expectedErr: ErrInvalidTrailingStop},
+		{line: "TRAILING STOP: 2%\nTRAIL: 3%", expectedErr: ErrTrailingStopAlreadySupplied},
+	}
+	for _, tc := range ts {
+		t.Run(tc.line, func(t *testing.T) {
+			output, err := NewSignalTranspiler().Transpile(exitsTestSignal + tc.line)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if output.TrailingStopPercentage != tc.expected {
+				t.Errorf("expected %v%%, got %v%%", tc.expected, output.TrailingStopPercentage)
+			}
+			if line := joinTokens(output.TokenizedInput[4]); line != tc.expectedLine {
+				t.Errorf("expected the line to read %q, got %q", tc.expectedLine, line)
+			}
+		})
+	}
+}
+
+func TestBreakEven(t *testing.T) {
+	ts := []struct {
+		line         string
+		expected     int
+		expectedLine string
+		expectedErr  error
+	}{
+		{line: "BREAK EVEN AFTER TP1", expected: 1, expectedLine: "BREAK EVEN AFTER TP1"},
+		{line: "breakeven after take profit 2", expected: 2, expectedLine: "BREAK EVEN AFTER TP2"},
+		{line: "MOVE SL TO ENTRY AFTER TP 1", expected: 1, expectedLine: "BREAK EVEN AFTER TP1"},
+		{line: "STOP TO BREAKEVEN AFTER TP2", expected: 2, expectedLine: "BREAK EVEN AFTER TP2"},
+		{line: "BREAK EVEN AFTER TP0", expectedErr: ErrMalformedInteger},
+		{line: "BREAK EVEN AFTER TP3", expectedErr: ErrBreakEvenAfterNoTakeProfit},
+		{line: "BREAK EVEN AFTER TP1\nBREAK EVEN AFTER TP2", expectedErr: ErrBreakEvenAlreadySupplied},
+	}
+	for _, tc := range ts {
+		t.Run(tc.line, func(t *testing.T) {
+			output, err := NewSignalTranspiler().Transpile(exitsTestSignal + tc.line)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if output.BreakEvenAfterTakeProfit != tc.expected {
+				t.Errorf("expected TP%v, got TP%v", tc.expected, output.BreakEvenAfterTakeProfit)
+			}
+			if line := joinTokens(output.TokenizedInput[4]); line != tc.expectedLine {
+				t.Errorf("expected the line to read %q, got %q", tc.expectedLine, line)
+			}
+		})
+	}
+}
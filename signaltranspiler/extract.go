@@ -0,0 +1,358 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+var ErrExtractedField = errors.New("extracted from free-form text")
+
+const (
+	CONFIDENCE_HIGH   = "high"
+	CONFIDENCE_MEDIUM = "medium"
+	CONFIDENCE_LOW    = "low"
+)
+
+// WithExtraction reads signals as free-form text, e.g. "Buying ETH/USDT now around 1800, targets 1900 and 2000, stop
+// 1700", instead of one instruction per line. Signals are still separated by "---".
+func WithExtraction(extraction bool) Option {
+	return func(t *SignalTranspiler) {
+		t.extraction = extraction
+	}
+}
+
+var (
+	rxExtractDate      = regexp.MustCompile(`\b(\d{4}-\d{2}-\d{2}(?:[T ]\d{1,2}:\d{2}(?::\d{2})?(?:Z|[+-]\d{2}:?\d{2})?)?|\d{1,2}/\d{1,2}/\d{4}(?: \d{1,2}:\d{2})?)\b`)
+	rxExtractStopLoss  = regexp.MustCompile(`\b(STOP[- ]?LOSS|STOP|SL|INVALIDATION)\b\s*(?::|AT|BELOW|ABOVE|@)?\s*(\d+(?:\.\d+)?\s*%?)`)
+	rxExtractTarget    = regexp.MustCompile(`\b(TAKE[- ]?PROFITS?|TARGETS?|TPS?\d*)\b\s*(?::|AT|@)?\s*(\d+(?:\.\d+)?%?(?:(?:\s*(?:,|AND|/|&)\s*|\s+)\d+(?:\.\d+)?%?)*)`)
+	rxExtractEnter     = regexp.MustCompile(`\b(ENTRY(?: ZONE| PRICE)?|ENTER(?: AT)?|BETWEEN|AROUND|NEAR|AT|@)(?:\s+|\s*:\s*|\b)(\d+(?:\.\d+)?)(?:\s*(?:-|TO|AND)\s*(\d+(?:\.\d+)?))?`)
+	rxExtractImmediate = regexp.MustCompile(`\b(NOW|AT MARKET|MARKET PRICE|CMP|IMMEDIATELY)\b`)
+	rxExtractDirection = regexp.MustCompile(`\b(LONG|SHORT|BUY|BUYING|BOUGHT|SELL|SELLING|SOLD|BULLISH|BEARISH)\b`)
+	rxExtractExchange  = regexp.MustCompile(`\b([A-Z]+(?:\.[A-Z]+)?)\b`)
+	rxExtractNumber    = regexp.MustCompile(`\d+(?:\.\d+)?%?`)
+)
+
+// extraction is what extractLines found in the text: the instructions it stands for, with the spans they came from.
+type extraction struct {
+	text         string
+	upText       string
+	lineStarts   []int
+	firstLine    int
+	used         []bool
+	spanIDs      []int
+	tokenTypes   []string
+	instructions []*signalInstruction
+	confidences  []string
+	excerpts     []string
+	spans        int
+}
+
+// extractLines scans free-form text for a market, direction, entry, take profits, stop loss, exchange and start date,
+// and transpiles them as if they were instructions. Every extracted field gets a warning with its confidence, and the
+// tokens show which parts of the text were used.
+func (t SignalTranspiler) extractLines(lines []string, firstLine int) SignalTranspilerOutput {
+	e := newExtraction(lines, firstLine)
+	e.extractDate(t)
+	e.extractMarket(t)
+	e.extractStopLoss()
+	e.extractTakeProfits()
+	e.extractEnter()
+	e.extractDirection()
+	e.extractExchange()
+
+	signalInstructions := e.instructions
+	if !e.has(instrNameStartAt) {
+		// A message without a date is usually about right now
+		si := newSignalInstruction("START AT: "+t.currentTime().UTC().Truncate(time.Minute).Format(time.RFC3339), 0, true)
+		e.instructions = append(e.instructions, si)
+		e.confidences = append(e.confidences, CONFIDENCE_LOW)
+		e.excerpts = append(e.excerpts, "the current time")
+		signalInstructions = e.instructions
+	}
+
+	output := t.transpileInstructions(signalInstructions)
+	for i, si := range e.instructions {
+		instruction := joinTokens(si.tokenizedInput)
+		if i := strings.Index(instruction, " // INFERRED"); i != -1 {
+			instruction = instruction[:i]
+		}
+		output.addWarning(fmt.Errorf("%w with %v confidence: %v from [%v]", ErrExtractedField, e.confidences[i], instruction, e.excerpts[i]), si)
+	}
+	output.TokenizedInput = e.tokenize()
+	return output
+}
+
+func newExtraction(lines []string, firstLine int) *extraction {
+	text := strings.Join(lines, "\n")
+	e := &extraction{
+		text:       text,
+		upText:     asciiUpper(text),
+		lineStarts: []int{0},
+		firstLine:  firstLine,
+		used:       make([]bool, len(text)),
+		spanIDs:    make([]int, len(text)),
+		tokenTypes: make([]string, len(text)),
+	}
+	for i, c := range text {
+		if c == '\n' {
+			e.lineStarts = append(e.lineStarts, i+1)
+		}
+	}
+	return e
+}
+
+// asciiUpper uppercases only ASCII letters, so that byte offsets in the result are the same as in s.
+func asciiUpper(s string) string {
+	bs := []byte(s)
+	for i, b := range bs {
+		if b >= 'a' && b <= 'z' {
+			bs[i] = b - 'a' + 'A'
+		}
+	}
+	return string(bs)
+}
+
+// find returns the first match of rx that doesn't overlap text already used by another field.
+func (e *extraction) find(rx *regexp.Regexp) []int {
+	for _, match := range rx.FindAllStringSubmatchIndex(e.upText, -1) {
+		if !e.isUsed(match[0], match[1]) {
+			return match
+		}
+	}
+	return nil
+}
+
+func (e *extraction) findAll(rx *regexp.Regexp) [][]int {
+	matches := [][]int{}
+	for _, match := range rx.FindAllStringSubmatchIndex(e.upText, -1) {
+		if !e.isUsed(match[0], match[1]) {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+func (e *extraction) isUsed(start, end int) bool {
+	for i := start; i < end; i++ {
+		if e.used[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// use marks the match as used by an instruction: the keyword in group keywordGroup, if any, and the rest as values.
+func (e *extraction) use(match []int, keywordGroup int) {
+	e.spans++
+	for i := match[0]; i < match[1]; i++ {
+		e.used[i] = true
+		e.spanIDs[i] = e.spans
+		e.tokenTypes[i] = TOKEN_EXPRESSION
+	}
+	if keywordGroup > 0 && match[2*keywordGroup] != -1 {
+		for i := match[2*keywordGroup]; i < match[2*keywordGroup+1]; i++ {
+			e.tokenTypes[i] = TOKEN_INSTRUCTION
+		}
+	}
+}
+
+// add records the instruction that a match stands for.
+func (e *extraction) add(rawInput string, match []int, confidence string) {
+	line := sort.SearchInts(e.lineStarts, match[0]+1) - 1
+	lineStart := e.lineStarts[line]
+	lineEnd := len(e.text)
+	if line+1 < len(e.lineStarts) {
+		lineEnd = e.lineStarts[line+1] - 1
+	}
+	end := match[1]
+	if end > lineEnd {
+		end = lineEnd
+	}
+	si := newSignalInstruction(rawInput, e.firstLine+line, false)
+	si.span = []int{
+		utf8.RuneCountInString(e.text[lineStart:match[0]]),
+		utf8.RuneCountInString(e.text[lineStart:end]),
+	}
+	e.instructions = append(e.instructions, si)
+	e.confidences = append(e.confidences, confidence)
+	e.excerpts = append(e.excerpts, strings.Join(strings.Fields(e.text[match[0]:match[1]]), " "))
+}
+
+func (e *extraction) has(name string) bool {
+	for _, si := range e.instructions {
+		if strings.HasPrefix(si.rawInput, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *extraction) extractDate(t SignalTranspiler) {
+	for _, match := range e.findAll(rxExtractDate) {
+		parsed, err := t.parseDate(e.text[match[0]:match[1]], nil)
+		if err != nil || parsed.Date.IsZero() {
+			continue
+		}
+		e.use(match, 0)
+		e.add("START AT: "+parsed.Date.UTC().Format(time.RFC3339), match, CONFIDENCE_MEDIUM)
+		return
+	}
+}
+
+func (e *extraction) extractMarket(t SignalTranspiler) {
+	if match := e.find(rxDialectPair); match != nil {
+		e.use(match, 0)
+		e.add("MARKET: "+e.upText[match[2]:match[3]]+"/"+e.upText[match[4]:match[5]], match, CONFIDENCE_HIGH)
+		return
+	}
+	// Channels often tag the market instead, e.g. #BTCUSDT
+	for _, match := range e.findAll(rxHashtagPair) {
+		if base, quote, ok := resolvePair(e.upText[match[2]:match[3]], t.marketCatalog, t.defaultExchangeOrDefault()); ok {
+			e.use(match, 0)
+			e.add("MARKET: "+base+"/"+quote, match, CONFIDENCE_HIGH)
+			return
+		}
+	}
+}
+
+func (e *extraction) extractStopLoss() {
+	if match := e.find(rxExtractStopLoss); match != nil {
+		e.use(match, 1)
+		e.add("STOP LOSS: "+strings.Replace(e.upText[match[4]:match[5]], " ", "", -1), match, CONFIDENCE_HIGH)
+	}
+}
+
+func (e *extraction) extractTakeProfits() {
+	values, excerpts := []string{}, []string{}
+	var first, last []int
+	for _, match := range e.findAll(rxExtractTarget) {
+		e.use(match, 1)
+		values = append(values, rxExtractNumber.FindAllString(e.upText[match[4]:match[5]], -1)...)
+		excerpts = append(excerpts, strings.Join(strings.Fields(e.text[match[0]:match[1]]), " "))
+		if first == nil {
+			first = match
+		}
+		last = match
+	}
+	if first != nil {
+		// N.B. the span goes from the first target to the last one, as far as the first target's line
+		e.add("TAKE PROFIT: "+strings.Join(values, ", "), []int{first[0], last[1]}, CONFIDENCE_HIGH)
+		e.excerpts[len(e.excerpts)-1] = strings.Join(excerpts, ", ")
+	}
+}
+
+// extractEnter looks for an entry price or range. Words like "entry" make it certain; "around" or "at" make it likely.
+func (e *extraction) extractEnter() {
+	if match := e.find(rxExtractEnter); match != nil {
+		low, _ := strconv.ParseFloat(e.upText[match[4]:match[5]], 64)
+		high := low
+		if match[6] != -1 {
+			high, _ = strconv.ParseFloat(e.upText[match[6]:match[7]], 64)
+		}
+		if low > high {
+			low, high = high, low
+		}
+		confidence := CONFIDENCE_MEDIUM
+		if keyword := e.upText[match[2]:match[3]]; strings.HasPrefix(keyword, "ENT") || keyword == "BETWEEN" {
+			confidence = CONFIDENCE_HIGH
+		}
+		e.use(match, 1)
+		e.add(fmt.Sprintf("ENTER BETWEEN: %v - %v", renderFloat(common.JsonFloat64(low)), renderFloat(common.JsonFloat64(high))), match, confidence)
+		return
+	}
+	if match := e.find(rxExtractImmediate); match != nil {
+		e.use(match, 0)
+		e.add("ENTER: IMMEDIATELY", match, CONFIDENCE_MEDIUM)
+	}
+}
+
+// extractDirection looks for words like "long" or "selling". Without them, the direction follows the take profits.
+func (e *extraction) extractDirection() {
+	if match := e.find(rxExtractDirection); match != nil {
+		word := e.upText[match[2]:match[3]]
+		direction, confidence := "LONG", CONFIDENCE_MEDIUM
+		switch word {
+		case "LONG":
+			confidence = CONFIDENCE_HIGH
+		case "SHORT":
+			direction, confidence = "SHORT", CONFIDENCE_HIGH
+		case "SELL", "SELLING", "SOLD", "BEARISH":
+			direction = "SHORT"
+		}
+		e.use(match, 1)
+		e.add(direction, match, confidence)
+		return
+	}
+	var enter, takeProfit *signalInstruction
+	for _, si := range e.instructions {
+		switch {
+		case strings.HasPrefix(si.rawInput, "ENTER") && !strings.HasSuffix(si.rawInput, "IMMEDIATELY"):
+			enter = si
+		case strings.HasPrefix(si.rawInput, "TAKE PROFIT") && !strings.Contains(si.rawInput, "%"):
+			takeProfit = si
+		}
+	}
+	if enter == nil || takeProfit == nil {
+		return
+	}
+	enterPrices := rxExtractNumber.FindAllString(enter.rawInput, -1)
+	takeProfitPrices := rxExtractNumber.FindAllString(takeProfit.rawInput, -1)
+	low, _ := strconv.ParseFloat(enterPrices[0], 64)
+	firstTakeProfit, _ := strconv.ParseFloat(takeProfitPrices[0], 64)
+	if firstTakeProfit < low {
+		si := newSignalInstruction("SHORT", takeProfit.lineNumber, false)
+		si.span = takeProfit.span
+		e.instructions = append(e.instructions, si)
+		e.confidences = append(e.confidences, CONFIDENCE_LOW)
+		e.excerpts = append(e.excerpts, "take profits below the entry")
+	}
+}
+
+func (e *extraction) extractExchange() {
+	for _, match := range e.findAll(rxExtractExchange) {
+		if word := e.upText[match[2]:match[3]]; isSInSS(word, exchangeList) {
+			e.use(match, 0)
+			e.add("EXCHANGE: "+word, match, CONFIDENCE_MEDIUM)
+			return
+		}
+	}
+}
+
+// tokenize splits every line of the text into the parts used by some instruction and the parts that were ignored,
+// which are shown as comments.
+func (e *extraction) tokenize() [][]InputToken {
+	tokenizedInput := [][]InputToken{}
+	for line, start := range e.lineStarts {
+		end := len(e.text)
+		if line+1 < len(e.lineStarts) {
+			end = e.lineStarts[line+1] - 1
+		}
+		tokens := []InputToken{}
+		if strings.TrimSpace(e.text[start:end]) == "---" {
+			tokenizedInput = append(tokenizedInput, append(tokens, InputToken{Input: e.text[start:end], TokenType: TOKEN_PUNCTUATION}))
+			continue
+		}
+		for i := start; i < end; {
+			tokenType, spanID := e.tokenTypes[i], e.spanIDs[i]
+			j := i
+			for j < end && e.tokenTypes[j] == tokenType && e.spanIDs[j] == spanID {
+				j++
+			}
+			if tokenType == "" {
+				tokenType = TOKEN_COMMENT
+			}
+			tokens = append(tokens, InputToken{Input: e.text[i:j], TokenType: tokenType})
+			i = j
+		}
+		tokenizedInput = append(tokenizedInput, tokens)
+	}
+	return tokenizedInput
+}
@@ -0,0 +1,148 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// extractedField is what the warning of an extracted field says, and where in the text it points.
+type extractedField struct {
+	confidence, instruction, excerpt string
+	line, columnStart, columnEnd     int
+}
+
+func TestExtraction(t *testing.T) {
+	ts := []struct {
+		name          string
+		text          string
+		expected      []extractedField
+		expectedUsed  []string
+		expectedError error
+	}{
+		{
+			name: "entry around a price",
+			text: "Buying ETH/USDT now around 1800, targets 1900 and 2000, stop 1700",
+			expected: []extractedField{
+				{CONFIDENCE_HIGH, "MARKET: ETH/USDT", "ETH/USDT", 0, 7, 15},
+				{CONFIDENCE_HIGH, "STOP LOSS: 1700", "stop 1700", 0, 56, 65},
+				{CONFIDENCE_HIGH, "TAKE PROFIT: 1900, 2000", "targets 1900 and 2000", 0, 33, 54},
+				{CONFIDENCE_MEDIUM, "ENTER BETWEEN: 1800 - 1800", "around 1800", 0, 20, 31},
+				{CONFIDENCE_MEDIUM, "LONG", "Buying", 0, 0, 6},
+				{CONFIDENCE_LOW, "START AT: 2021-06-22T18:00:00Z", "the current time", -1, 0, 0},
+			},
+			expectedUsed: []string{"Buying", "ETH/USDT", "around", " 1800", "targets", " 1900 and 2000", "stop", " 1700"},
+		},
+		{
+			name: "every field, including the exchange and date",
+			text: "BTC/USDT short on kraken, entry 31000 - 32000, TP1 30000 TP2 29000, SL 33000, 2021-06-22 15:21",
+			expected: []extractedField{
+				{CONFIDENCE_MEDIUM, "START AT: 2021-06-22T15:21:00Z", "2021-06-22 15:21", 0, 78, 94},
+				{CONFIDENCE_HIGH, "MARKET: BTC/USDT", "BTC/USDT", 0, 0, 8},
+				{CONFIDENCE_HIGH, "STOP LOSS: 33000", "SL 33000", 0, 68, 76},
+				{CONFIDENCE_HIGH, "TAKE PROFIT: 30000, 29000", "TP1 30000, TP2 29000", 0, 47, 66},
+				{CONFIDENCE_HIGH, "ENTER BETWEEN: 31000 - 32000", "entry 31000 - 32000", 0, 26, 45},
+				{CONFIDENCE_HIGH, "SHORT", "short", 0, 9, 14},
+				{CONFIDENCE_MEDIUM, "EXCHANGE: KRAKEN", "kraken", 0, 18, 24},
+			},
+			expectedUsed: []string{"BTC/USDT", "short", "kraken", "entry", " 31000 - 32000", "TP1", " 30000", "TP2", " 29000", "SL", " 33000", "2021-06-22 15:21"},
+		},
+		{
+			name: "hashtag market over several lines",
+			text: "#SOLUSDT bullish\nentry zone 30-32\ntargets 35 / 40\nstop loss 28\nPosted 22/06/2021 15:21",
+			expected: []extractedField{
+				{CONFIDENCE_MEDIUM, "START AT: 2021-06-22T15:21:00Z", "22/06/2021 15:21", 4, 7, 23},
+				{CONFIDENCE_HIGH, "MARKET: SOL/USDT", "#SOLUSDT", 0, 0, 8},
+				{CONFIDENCE_HIGH, "STOP LOSS: 28", "stop loss 28", 3, 0, 12},
+				{CONFIDENCE_HIGH, "TAKE PROFIT: 35, 40", "targets 35 / 40", 2, 0, 15},
+				{CONFIDENCE_HIGH, "ENTER BETWEEN: 30 - 32", "entry zone 30-32", 1, 0, 16},
+				{CONFIDENCE_MEDIUM, "LONG", "bullish", 0, 9, 16},
+			},
+			expectedUsed: []string{"#SOLUSDT", "bullish", "entry zone", " 30-32", "targets", " 35 / 40", "stop loss", " 28", "22/06/2021 15:21"},
+		},
+		{
+			name: "percentages at market price",
+			text: "ADA/USDT at market, TP 5% 10%, SL 3%",
+			expected: []extractedField{
+				{CONFIDENCE_HIGH, "MARKET: ADA/USDT", "ADA/USDT", 0, 0, 8},
+				{CONFIDENCE_HIGH, "STOP LOSS: 3%", "SL 3%", 0, 31, 36},
+				{CONFIDENCE_HIGH, "TAKE PROFIT: 5%, 10%", "TP 5% 10%", 0, 20, 29},
+				{CONFIDENCE_MEDIUM, "ENTER: IMMEDIATELY", "at market", 0, 9, 18},
+				{CONFIDENCE_LOW, "START AT: 2021-06-22T18:00:00Z", "the current time", -1, 0, 0},
+			},
+			expectedUsed: []string{"ADA/USDT", "at market", "TP", " 5% 10%", "SL", " 3%"},
+		},
+		{
+			name: "direction from take profits below the entry",
+			text: "LINK/USDT entry 20, targets 18, 16, stop 22",
+			expected: []extractedField{
+				{CONFIDENCE_HIGH, "MARKET: LINK/USDT", "LINK/USDT", 0, 0, 9},
+				{CONFIDENCE_HIGH, "STOP LOSS: 22", "stop 22", 0, 36, 43},
+				{CONFIDENCE_HIGH, "TAKE PROFIT: 18, 16", "targets 18, 16", 0, 20, 34},
+				{CONFIDENCE_HIGH, "ENTER BETWEEN: 20 - 20", "entry 20", 0, 10, 18},
+				{CONFIDENCE_LOW, "SHORT", "take profits below the entry", 0, 20, 34},
+				{CONFIDENCE_LOW, "START AT: 2021-06-22T18:00:00Z", "the current time", -1, 0, 0},
+			},
+			expectedUsed: []string{"LINK/USDT", "entry", " 20", "targets", " 18, 16", "stop", " 22"},
+		},
+		{
+			name:          "no signal",
+			text:          "Great day everyone!",
+			expected:      []extractedField{{CONFIDENCE_LOW, "START AT: 2021-06-22T18:00:00Z", "the current time", -1, 0, 0}},
+			expectedUsed:  []string{},
+			expectedError: ErrMarketRequired,
+		},
+	}
+	transpiler := NewSignalTranspiler(WithExtraction(true), WithClock(func() time.Time { return time.Date(2021, 6, 22, 18, 0, 0, 0, time.UTC) }))
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := transpiler.Transpile(tc.text)
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("expected error %v, got %v", tc.expectedError, err)
+			}
+
+			expected := []Diagnostic{}
+			for _, f := range tc.expected {
+				expected = append(expected, Diagnostic{
+					Code:        diagnosticCode(ErrExtractedField),
+					Severity:    SEVERITY_WARNING,
+					Line:        f.line,
+					ColumnStart: f.columnStart,
+					ColumnEnd:   f.columnEnd,
+					Message:     fmt.Sprintf("%v with %v confidence: %v from [%v]", ErrExtractedField, f.confidence, f.instruction, f.excerpt),
+				})
+			}
+			actual := []Diagnostic{}
+			for _, d := range output.Diagnostics {
+				if d.Severity == SEVERITY_WARNING {
+					actual = append(actual, d)
+				}
+			}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("expected %+v\ngot      %+v", expected, actual)
+			}
+
+			// The tokens keep the text as it is, and what wasn't used is a comment
+			lines, used := []string{}, []string{}
+			for _, line := range output.TokenizedInput {
+				text := ""
+				for _, token := range line {
+					text += token.Input
+					if token.TokenType != TOKEN_COMMENT {
+						used = append(used, token.Input)
+					}
+				}
+				lines = append(lines, text)
+			}
+			if text := strings.Join(lines, "\n"); text != tc.text {
+				t.Errorf("expected the tokens to read %q, got %q", tc.text, text)
+			}
+			if !reflect.DeepEqual(used, tc.expectedUsed) {
+				t.Errorf("expected to use %q, got %q", tc.expectedUsed, used)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package signaltranspiler
+
+import (
+	"sort"
+	"strings"
+)
+
+// formatOrder is the order in which Format lays out instructions. Instructions not listed go last.
+var formatOrder = []string{
+	instrNameStrict,
+	instrNameMarket,
+	instrNameExchange,
+	instrNameDirection,
+	instrNameLeverage,
+	instrNameMarginMode,
+	instrNameTimezone,
+	instrNameStartAt,
+	instrNameEnter,
+	instrNameTakeProfit,
+	instrNameStopLoss,
+	instrNameTrailing,
+	instrNameBreakEven,
+	instrNameTimeout,
+}
+
+// Format transpiles the input and renders it back as normalized signal text, without inferred instructions.
+func Format(input string) (string, error) {
+	return NewSignalTranspiler().Format(input, false)
+}
+
+// Format transpiles the input and renders it back as normalized signal text: one canonical instruction per line, in a
+// stable order, with signals separated by "---". Comments are kept: comments at the top of a signal stay there, other
+// comment lines move together with the instruction that follows them. If includeInferred is set, inferred defaults
+// are included with their "// INFERRED" comment. Signals written in a dialect come out as built-in instructions.
+func (t SignalTranspiler) Format(input string, includeInferred bool) (string, error) {
+	outputs, err := t.TranspileAll(input)
+	if err != nil {
+		return "", err
+	}
+	signals := []string{}
+	for _, output := range outputs {
+		signals = append(signals, formatInstructions(output.instructions, includeInferred))
+	}
+	return strings.Join(signals, "\n---\n"), nil
+}
+
+type formattedLine struct {
+	order    int
+	comments []string
+	line     string
+}
+
+func formatInstructions(signalInstructions []*signalInstruction, includeInferred bool) string {
+	var (
+		header   = []string{}
+		lines    = []formattedLine{}
+		comments = []string{}
+	)
+	for _, si := range signalInstructions {
+		switch {
+		case si.isInferred && !includeInferred, si.name == instrNameSeparator:
+			continue
+		case si.name == instrNameDialect:
+			// The formatted signal is made of built-in instructions, so it doesn't need a dialect anymore
+			continue
+		case si.name == instrNameEmpty:
+			if comment := trailingComment(si.rawInput); comment != "" {
+				comments = append(comments, comment)
+			}
+			continue
+		}
+		if si.isInferred {
+			lines = append(lines, formattedLine{order: formatOrderOf(si.name), line: joinTokens(si.tokenizedInput)})
+			continue
+		}
+		if len(lines) == 0 {
+			header, comments = comments, []string{}
+		}
+		lines = append(lines, formattedLine{order: formatOrderOf(si.name), comments: comments, line: joinTokens(si.tokenizedInput)})
+		comments = []string{}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].order < lines[j].order })
+
+	result := header
+	for _, line := range lines {
+		result = append(result, line.comments...)
+		result = append(result, line.line)
+	}
+	result = append(result, comments...)
+	return strings.Join(result, "\n")
+}
+
+func formatOrderOf(name string) int {
+	for i, n := range formatOrder {
+		if n == name {
+			return i
+		}
+	}
+	return len(formatOrder)
+}
+
+func joinTokens(tokens []InputToken) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString(token.Input)
+	}
+	return strings.TrimRight(sb.String(), " ")
+}
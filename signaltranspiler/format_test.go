@@ -0,0 +1,66 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	ts := []struct {
+		name        string
+		input       string
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:     "reordered and normalized",
+			input:    "tp: 32000, 33000\nsl 29000\nbtc/usdt\nEnter 30000-31000\nstart at: 2021-06-22T15:21:00Z",
+			expected: "MARKET: BTC/USDT\nSTART AT: 2021-06-22T15:21:00Z\nENTER BETWEEN: 30000 - 31000\nTAKE PROFIT: 32000, 33000\nSTOP LOSS: 29000",
+		},
+		{
+			name:     "comments",
+			input:    "// channel A\nBTC/USDT\n// second entry\nENTER: 30000 - 31000 // wide\nSTART AT: 2021-06-22T15:21:00Z",
+			expected: "// channel A\nMARKET: BTC/USDT\nSTART AT: 2021-06-22T15:21:00Z\n// second entry\nENTER BETWEEN: 30000 - 31000 // wide",
+		},
+		{
+			name:     "several signals",
+			input:    "BTC/USDT\nSTART AT: 2021-06-22T15:21:00Z\nENTER: 30000 - 31000\nETH/USDT\nSHORT\nSTART AT: 2021-06-22T15:21:00Z\nENTER: 2000 - 2100",
+			expected: "MARKET: BTC/USDT\nSTART AT: 2021-06-22T15:21:00Z\nENTER BETWEEN: 30000 - 31000\n---\nMARKET: ETH/USDT\nSHORT\nSTART AT: 2021-06-22T15:21:00Z\nENTER BETWEEN: 2000 - 2100",
+		},
+		{
+			name:        "errors",
+			input:       "BTC/USDT\nFOO",
+			expectedErr: ErrUnrecognizedInstruction,
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			formatted, err := Format(tc.input)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if formatted != tc.expected {
+				t.Fatalf("expected\n%v\ngot\n%v", tc.expected, formatted)
+			}
+			// Formatting is idempotent
+			if again, err := Format(formatted); err != nil || again != formatted {
+				t.Errorf("expected formatting again to change nothing, got\n%v\n%v", again, err)
+			}
+		})
+	}
+}
+
+func TestFormatInferred(t *testing.T) {
+	transpiler := NewSignalTranspiler()
+	formatted, err := transpiler.Format("BTC/USDT\nENTER: 30000 - 31000\nSTART AT: 2021-06-22T15:21:00Z", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, err := transpiler.Format(formatted, true)
+	if err != nil || again != formatted {
+		t.Errorf("expected formatting again to change nothing, got\n%v\n%v\nfrom\n%v", again, err, formatted)
+	}
+}
@@ -14,26 +14,80 @@ import (
 var instructions = []instruction{
 	instrMarket{},
 	instrEmpty{},
+	instrSeparator{},
+	instrTimezone{},
+	instrStrict{},
+	instrDialect{},
 	instrEnterImmediately{},
 	instrEnter{},
+	instrTakeProfitPercentage{},
 	instrTakeProfit{},
+	instrStopLossPercentage{},
 	instrStopLoss{},
+	instrTrailingStop{},
+	instrBreakEven{},
+	instrLeverage{},
+	instrMarginMode{},
 	instrExchange{},
+	instrInvalidateAt{},
 	instrInitialISO8601{},
 	instrIsShort{},
 	instrInvalidate{},
 }
 
+// directiveNames are the built-in instructions that are directives (see InstructionMatcher).
+var directiveNames = map[string]bool{
+	instrNameTimezone: true,
+	instrNameStrict:   true,
+	instrNameDialect:  true,
+}
+
+const (
+	instrNameMarket     = "MARKET"
+	instrNameEmpty      = "EMPTY"
+	instrNameSeparator  = "SEPARATOR"
+	instrNameEnter      = "ENTER"
+	instrNameTakeProfit = "TAKE PROFIT"
+	instrNameStopLoss   = "STOP LOSS"
+	instrNameTrailing   = "TRAILING STOP"
+	instrNameBreakEven  = "BREAK EVEN"
+	instrNameLeverage   = "LEVERAGE"
+	instrNameMarginMode = "MARGIN MODE"
+	instrNameExchange   = "EXCHANGE"
+	instrNameTimezone   = "TIMEZONE"
+	instrNameStrict     = "STRICT"
+	instrNameDialect    = "DIALECT"
+	instrNameStartAt    = "START AT"
+	instrNameDirection  = "DIRECTION"
+	instrNameTimeout    = "TIMEOUT"
+)
+
+// maxLeverage is the highest leverage futures exchanges offer.
+const maxLeverage = 125
+
 var (
-	rxPair              = regexp.MustCompile(`^\s*(PAIR:?|SYMBOL:?|MARKET:?)?\s*([[:upper:]]{2,6})[/-]([[:upper:]]{2,6})\s*(//.*)?$`)
+	rxPair              = regexp.MustCompile(`^\s*(PAIR:?|SYMBOL:?|MARKET:?)?\s*(#?[[:upper:][:digit:]]{2,16}(?:[/_-][[:upper:][:digit:]]{2,12})?)\s*(//.*)?$`)
 	rxEmpty             = regexp.MustCompile(`^\s*(//.*)?$`)
+	rxSeparator         = regexp.MustCompile(`^\s*-{3,}\s*(//.*)?$`)
 	rxEnterImmediately  = regexp.MustCompile(`^\s*(ENTER:?)\s*(NOW|IMMEDIATELY)\s*(//.*)?$`)
 	rxEnter             = regexp.MustCompile(`^\s*(ENTER:?|ENTER AT:?|ENTER BETWEEN:?|ENTER RANGE:?)\s*(([\d.]+\s*(,|-|AND)?\s*)+?)\s*(//.*)?$`)
-	rxTakeProfit        = regexp.MustCompile(`^\s*(TAKE PROFIT:?|TP:?)\s*(([\d.]+\s*[,-]?\s*)+?)\s*(//.*)?$`)
+	rxTakeProfit        = regexp.MustCompile(`^\s*((?:TAKE PROFIT|TP)(?:\s*\d{1,2}\b\s*:|\d{1,2}\b)?:?)\s*(([\d.]+\s*(\(\s*[\d.]+\s*%\s*\))?\s*[,-]?\s*)+?)\s*(//.*)?$`)
+	rxAllocated         = regexp.MustCompile(`([\d.]+)\s*(\(\s*([\d.]+)\s*%\s*\))?`)
+	rxTakeProfitPct     = regexp.MustCompile(`^\s*(TAKE PROFIT:?|TP:?)\s*((\s*[+-]?[\d.]+\s*%\s*(\([\d.\s]*\))?\s*,?)+)\s*(//.*)?$`)
+	rxStopLossPct       = regexp.MustCompile(`^\s*(STOP LOSS:?|SL:?)\s*([+-]?[\d.]+)\s*%\s*(\([\d.\s]*\))?\s*(//.*)?$`)
+	rxPercentage        = regexp.MustCompile(`[+-]?([\d.]+)\s*%`)
+	rxTrailingStop      = regexp.MustCompile(`^\s*(TRAILING STOP( LOSS)?:?|TRAILING SL:?|TRAIL( STOP)?:?)\s*([\d.]+)\s*%\s*(//.*)?$`)
+	rxBreakEven         = regexp.MustCompile(`^\s*(BREAK ?EVEN|(MOVE )?(SL|STOP LOSS|STOP) TO (ENTRY|BREAK ?EVEN)) AFTER (TP|TAKE PROFIT) ?(\d+)\s*(//.*)?$`)
+	rxLeverage          = regexp.MustCompile(`^\s*(LEVERAGE:?|LEV:?)\s*(CROSS|ISOLATED)?\s*\(?\s*([\d.]+)\s*X?\s*\)?\s*(//.*)?$`)
+	rxMarginMode        = regexp.MustCompile(`^\s*(MARGIN( MODE)?:?)?\s*(CROSS|ISOLATED)( MARGIN)?\s*(//.*)?$`)
 	rxStopLoss          = regexp.MustCompile(`^\s*(STOP LOSS:?|SL:?)?\s*([\d.]+)\s*(//.*)?$`)
+	rxNoStopLoss        = regexp.MustCompile(`^\s*(STOP LOSS:?|SL:?)\s*(NONE|NO)\s*(//.*)?$`)
 	rxExchange          = regexp.MustCompile(`^\s*(EXCHANGE:?|PLATFORM:?)?\s*([[:upper:]]+)\s*(//.*)?$`)
-	rxInitialISO8601    = regexp.MustCompile(`^\s*(START AT:?|INITIALISO8601:?|FROM:?|AT:?|START:?)?\s*(.+?)\s*(//.*)?$`)
-	rxInvalidateISO8601 = regexp.MustCompile(`^\s*((TIMEOUT|INVALIDATE) (IN|AFTER|WITHIN):?)?\s*([\d]+?)\s+DAYS\s*(//.*)?$`)
+	rxStrict            = regexp.MustCompile(`^\s*STRICT( MODE)?\s*(//.*)?$`)
+	rxTimezone          = regexp.MustCompile(`(?i)^\s*(TIMEZONE:?|TIME ZONE:?|TZ:?)\s*(\S+)\s*(//.*)?$`)
+	rxInitialISO8601    = regexp.MustCompile(`(?i)^\s*(START AT:?|INITIALISO8601:?|FROM:?|AT:?|START:?)?\s*(.+?)\s*(//.*)?$`)
+	rxInvalidateISO8601 = regexp.MustCompile(`^\s*((TIMEOUT|INVALIDATE) (IN|AFTER|WITHIN):?)?\s*((\d+\s*(WEEKS?|W|DAYS?|D|HOURS?|HRS?|H|MINUTES?|MINS?|M)\s*)+?)\s*(//.*)?$`)
+	rxInvalidateAt      = regexp.MustCompile(`(?i)^\s*((?:INVALIDATE|TIMEOUT|EXPIRES?) AT:?)\s*(.+?)\s*(//.*)?$`)
 	rxIsShort           = regexp.MustCompile(`^\s*(LONG|SHORT)\s*(//.*)?$`)
 
 	exchangeList = []string{
@@ -66,6 +120,13 @@ var (
 		"BITMAX",
 	}
 
+	// futuresExchangeList are the exchanges where a signal can have leverage and a margin mode.
+	futuresExchangeList = []string{
+		"BINANCE FUTURES",
+		"BINANCEUSDMFUTURES",
+		"BINANCE USDM FUTURES",
+	}
+
 	supportedExchangeList = []string{
 		"BINANCE",
 		"BINANCE FUTURES",
@@ -80,24 +141,34 @@ var (
 
 type instrMarket struct{}
 
-func (si instrMarket) apply(rawInput string, sto *SignalTranspilerOutput) (signalInstruction, bool) {
+func (si instrMarket) name() string { return instrNameMarket }
+
+func (si instrMarket) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
 	upRawInput := strings.ToUpper(rawInput)
 	result := rxPair.FindStringSubmatch(upRawInput)
 	if len(result) == 0 {
-		return signalInstruction{}, false
+		return InstructionResult{}, false
+	}
+	exchange := sto.SignalInput.Exchange
+	if exchange == "" {
+		exchange = t.defaultExchangeOrDefault()
+	}
+	base, quote, ok := resolvePair(result[2], t.marketCatalog, exchange)
+	if !ok {
+		return InstructionResult{}, false
 	}
 	if sto.SignalInput.BaseAsset != "" || sto.SignalInput.QuoteAsset != "" {
-		return signalInstruction{
-			err: fmt.Errorf("%w [%v]", errMarketAlreadySupplied, rawInput),
-			tokenizedInput: []inputToken{
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrMarketAlreadySupplied, rawInput),
+			Tokens: []InputToken{
 				{Input: rawInput, TokenType: TOKEN_ERROR},
 			},
 		}, true
 	}
-	sto.SignalInput.BaseAsset = result[2]
-	sto.SignalInput.QuoteAsset = result[3]
-	return signalInstruction{
-		tokenizedInput: []inputToken{
+	sto.SignalInput.BaseAsset = base
+	sto.SignalInput.QuoteAsset = quote
+	return InstructionResult{
+		Tokens: []InputToken{
 			{Input: "MARKET", TokenType: TOKEN_INSTRUCTION},
 			{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 			{Input: sto.SignalInput.BaseAsset, TokenType: TOKEN_EXPRESSION},
@@ -109,31 +180,59 @@ func (si instrMarket) apply(rawInput string, sto *SignalTranspilerOutput) (signa
 
 type instrEmpty struct{}
 
-func (si instrEmpty) apply(rawInput string, sto *SignalTranspilerOutput) (signalInstruction, bool) {
+func (si instrEmpty) name() string { return instrNameEmpty }
+
+func (si instrEmpty) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
 	upRawInput := strings.ToUpper(rawInput)
 	result := rxEmpty.FindStringSubmatch(upRawInput)
 	if len(result) == 0 {
-		return signalInstruction{}, false
+		return InstructionResult{}, false
+	}
+	if comment := trailingComment(rawInput); comment != "" {
+		return InstructionResult{
+			Tokens: []InputToken{
+				{Input: comment, TokenType: TOKEN_COMMENT},
+			},
+		}, true
 	}
-	return signalInstruction{
-		tokenizedInput: []inputToken{
+	return InstructionResult{
+		Tokens: []InputToken{
 			{Input: " ", TokenType: TOKEN_PUNCTUATION},
 		},
 	}, true
 }
 
+type instrSeparator struct{}
+
+func (si instrSeparator) name() string { return instrNameSeparator }
+
+func (si instrSeparator) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
+	upRawInput := strings.ToUpper(rawInput)
+	result := rxSeparator.FindStringSubmatch(upRawInput)
+	if len(result) == 0 {
+		return InstructionResult{}, false
+	}
+	return InstructionResult{
+		Tokens: []InputToken{
+			{Input: "---", TokenType: TOKEN_PUNCTUATION},
+		},
+	}, true
+}
+
 type instrEnterImmediately struct{}
 
-func (si instrEnterImmediately) apply(rawInput string, sto *SignalTranspilerOutput) (signalInstruction, bool) {
+func (si instrEnterImmediately) name() string { return instrNameEnter }
+
+func (si instrEnterImmediately) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
 	upRawInput := strings.ToUpper(rawInput)
 	result := rxEnterImmediately.FindStringSubmatch(upRawInput)
 	if len(result) == 0 {
-		return signalInstruction{}, false
+		return InstructionResult{}, false
 	}
 	sto.SignalInput.EnterRangeLow = -1
 	sto.SignalInput.EnterRangeHigh = -1
-	return signalInstruction{
-		tokenizedInput: []inputToken{
+	return InstructionResult{
+		Tokens: []InputToken{
 			{Input: "ENTER", TokenType: TOKEN_INSTRUCTION},
 			{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 			{Input: "IMMEDIATELY", TokenType: TOKEN_EXPRESSION},
@@ -143,62 +242,111 @@ func (si instrEnterImmediately) apply(rawInput string, sto *SignalTranspilerOutp
 
 type instrTakeProfit struct{}
 
-func (si instrTakeProfit) apply(rawInput string, sto *SignalTranspilerOutput) (signalInstruction, bool) {
+func (si instrTakeProfit) name() string { return instrNameTakeProfit }
+
+func (si instrTakeProfit) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
 	upRawInput := strings.ToUpper(rawInput)
 	result := rxTakeProfit.FindStringSubmatch(upRawInput)
 	if len(result) == 0 {
-		return signalInstruction{}, false
+		return InstructionResult{}, false
 	}
-	fls, err := extractFloatSequence(result[2])
-	if err != nil {
-		return signalInstruction{
-			err: fmt.Errorf("%w with content %v", errMalformedFloat, result[2]),
-			tokenizedInput: []inputToken{
+	fls, err := extractFloatSequence(rxAllocated.ReplaceAllString(result[2], "$1"))
+	allocations, allocationErr := extractAllocations(result[2])
+	if err != nil || allocationErr != nil {
+		if err == nil {
+			err = ErrMalformedFloat
+		}
+		return InstructionResult{
+			Err: fmt.Errorf("%w with content %v", err, result[2]),
+			Tokens: []InputToken{
 				{Input: "TAKE PROFIT", TokenType: TOKEN_INSTRUCTION},
 				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 				{Input: result[2], TokenType: TOKEN_ERROR},
 			},
 		}, true
 	}
-	tokenizedInput := []inputToken{
+	tokenizedInput := []InputToken{
 		{Input: "TAKE PROFIT", TokenType: TOKEN_INSTRUCTION},
 		{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 	}
 
-	for _, fl := range fls {
+	// Once any take profit has an allocation, every take profit gets one, and the missing ones are 0
+	allocated := len(sto.TakeProfitAllocations) > 0
+	for _, allocation := range allocations {
+		allocated = allocated || allocation > 0
+	}
+	if allocated {
+		for len(sto.TakeProfitAllocations) < len(sto.SignalInput.TakeProfits) {
+			sto.TakeProfitAllocations = append(sto.TakeProfitAllocations, 0)
+		}
+	}
+	for i, fl := range fls {
 		cfl := common.JsonFloat64(fl)
 		cfls, _ := json.Marshal(cfl)
-		tokenizedInput = append(tokenizedInput, inputToken{Input: string(cfls), TokenType: TOKEN_EXPRESSION})
-		tokenizedInput = append(tokenizedInput, inputToken{Input: " ", TokenType: TOKEN_PUNCTUATION})
+		if i > 0 {
+			tokenizedInput = append(tokenizedInput, InputToken{Input: ", ", TokenType: TOKEN_PUNCTUATION})
+		}
+		tokenizedInput = append(tokenizedInput, InputToken{Input: string(cfls), TokenType: TOKEN_EXPRESSION})
 		sto.SignalInput.TakeProfits = append(sto.SignalInput.TakeProfits, cfl)
+		if !allocated {
+			continue
+		}
+		sto.TakeProfitAllocations = append(sto.TakeProfitAllocations, common.JsonFloat64(allocations[i]/100))
+		if allocations[i] > 0 {
+			tokenizedInput = append(tokenizedInput,
+				InputToken{Input: " (", TokenType: TOKEN_PUNCTUATION},
+				InputToken{Input: fmt.Sprintf("%v%%", allocations[i]), TokenType: TOKEN_EXPRESSION},
+				InputToken{Input: ")", TokenType: TOKEN_PUNCTUATION},
+			)
+		}
 	}
 
-	return signalInstruction{
-		tokenizedInput: tokenizedInput,
+	return InstructionResult{
+		Tokens: tokenizedInput,
 	}, true
 }
 
+// extractAllocations returns the allocation of every take profit in e.g. "32000 (30%), 33000 (70%)", as a percentage
+// of the position, or 0 if it has none.
+func extractAllocations(s string) ([]float64, error) {
+	allocations := []float64{}
+	for _, match := range rxAllocated.FindAllStringSubmatch(s, -1) {
+		if match[3] == "" {
+			allocations = append(allocations, 0)
+			continue
+		}
+		allocation, err := strconv.ParseFloat(match[3], 64)
+		if err != nil {
+			return allocations, err
+		}
+		allocations = append(allocations, allocation)
+	}
+	return allocations, nil
+}
+
 type instrEnter struct{}
 
-func (si instrEnter) apply(rawInput string, sto *SignalTranspilerOutput) (signalInstruction, bool) {
+func (si instrEnter) name() string { return instrNameEnter }
+
+func (si instrEnter) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
 	upRawInput := strings.ToUpper(rawInput)
 	result := rxEnter.FindStringSubmatch(upRawInput)
 	if len(result) == 0 {
-		return signalInstruction{}, false
+		return InstructionResult{}, false
 	}
 	if sto.SignalInput.EnterRangeLow != common.JsonFloat64(0.0) || sto.SignalInput.EnterRangeHigh != common.JsonFloat64(0.0) {
-		return signalInstruction{
-			err: fmt.Errorf("%w [%v]", errEnterRangeAlreadySupplied, rawInput),
-			tokenizedInput: []inputToken{
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrEnterRangeAlreadySupplied, rawInput),
+			Tokens: []InputToken{
 				{Input: rawInput, TokenType: TOKEN_ERROR},
 			},
 		}, true
 	}
 	fls, err := extractFloatSequence(result[2])
 	if err != nil {
-		return signalInstruction{
-			err: fmt.Errorf("%w with content %v", errMalformedFloat, result[2]),
-			tokenizedInput: []inputToken{
+		return InstructionResult{
+			Err: fmt.Errorf("%w with content %v", err, result[2]),
+			Tokens: []InputToken{
 				{Input: "ENTER BETWEEN", TokenType: TOKEN_INSTRUCTION},
 				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 				{Input: result[2], TokenType: TOKEN_ERROR},
@@ -206,9 +354,9 @@ func (si instrEnter) apply(rawInput string, sto *SignalTranspilerOutput) (signal
 		}, true
 	}
 	if len(fls) != 2 {
-		return signalInstruction{
-			err: fmt.Errorf("%w [%v], supply exactly two values e.g. ENTER BETWEEN: 0.1 - 0.5", errInvalidEnterAt, result[2]),
-			tokenizedInput: []inputToken{
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v], supply exactly two values e.g. ENTER BETWEEN: 0.1 - 0.5", ErrInvalidEnterAt, result[2]),
+			Tokens: []InputToken{
 				{Input: "ENTER BETWEEN", TokenType: TOKEN_INSTRUCTION},
 				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 				{Input: result[2], TokenType: TOKEN_ERROR},
@@ -221,9 +369,9 @@ func (si instrEnter) apply(rawInput string, sto *SignalTranspilerOutput) (signal
 	cfls2, _ := json.Marshal(cfl2)
 
 	if fls[0] > fls[1] {
-		return signalInstruction{
-			err: fmt.Errorf("%w [%v], the second number in the range should be higher", errInvalidEnterRange, result[2]),
-			tokenizedInput: []inputToken{
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v], the second number in the range should be higher", ErrInvalidEnterRange, result[2]),
+			Tokens: []InputToken{
 				{Input: "ENTER BETWEEN", TokenType: TOKEN_INSTRUCTION},
 				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 				{Input: string(cfls1), TokenType: TOKEN_EXPRESSION},
@@ -235,8 +383,8 @@ func (si instrEnter) apply(rawInput string, sto *SignalTranspilerOutput) (signal
 
 	sto.SignalInput.EnterRangeLow = cfl1
 	sto.SignalInput.EnterRangeHigh = cfl2
-	return signalInstruction{
-		tokenizedInput: []inputToken{
+	return InstructionResult{
+		Tokens: []InputToken{
 			{Input: "ENTER BETWEEN", TokenType: TOKEN_INSTRUCTION},
 			{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 			{Input: string(cfls1), TokenType: TOKEN_EXPRESSION},
@@ -248,26 +396,40 @@ func (si instrEnter) apply(rawInput string, sto *SignalTranspilerOutput) (signal
 
 type instrStopLoss struct{}
 
-func (si instrStopLoss) apply(rawInput string, sto *SignalTranspilerOutput) (signalInstruction, bool) {
+func (si instrStopLoss) name() string { return instrNameStopLoss }
+
+func (si instrStopLoss) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
 	upRawInput := strings.ToUpper(rawInput)
 	result := rxStopLoss.FindStringSubmatch(upRawInput)
-	if len(result) == 0 {
-		return signalInstruction{}, false
+	isNone := rxNoStopLoss.MatchString(upRawInput)
+	if len(result) == 0 && !isNone {
+		return InstructionResult{}, false
 	}
-	if sto.SignalInput.StopLoss != common.JsonFloat64(0.0) {
-		return signalInstruction{
-			err: fmt.Errorf("%w [%v]", errStopLossAlreadySupplied, rawInput),
-			tokenizedInput: []inputToken{
+	if sto.SignalInput.StopLoss != common.JsonFloat64(0.0) || sto.StopLossPercentage != 0 {
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrStopLossAlreadySupplied, rawInput),
+			Tokens: []InputToken{
 				{Input: rawInput, TokenType: TOKEN_ERROR},
 			},
 		}, true
 	}
+	// STOP LOSS: NONE is the signal checker's -1
+	if isNone {
+		sto.SignalInput.StopLoss = -1
+		return InstructionResult{
+			Tokens: []InputToken{
+				{Input: "STOP LOSS", TokenType: TOKEN_INSTRUCTION},
+				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+				{Input: "NONE", TokenType: TOKEN_EXPRESSION},
+			},
+		}, true
+	}
 	maybeFloat := strings.ReplaceAll(result[2], ",", "")
 	fl, err := strconv.ParseFloat(maybeFloat, 64)
 	if err != nil {
-		return signalInstruction{
-			err: fmt.Errorf("%w with content %v", errMalformedFloat, result[2]),
-			tokenizedInput: []inputToken{
+		return InstructionResult{
+			Err: fmt.Errorf("%w with content %v", ErrMalformedFloat, result[2]),
+			Tokens: []InputToken{
 				{Input: "STOP LOSS", TokenType: TOKEN_INSTRUCTION},
 				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 				{Input: maybeFloat, TokenType: TOKEN_ERROR},
@@ -275,8 +437,8 @@ func (si instrStopLoss) apply(rawInput string, sto *SignalTranspilerOutput) (sig
 		}, true
 	}
 	sto.SignalInput.StopLoss = common.JsonFloat64(fl)
-	return signalInstruction{
-		tokenizedInput: []inputToken{
+	return InstructionResult{
+		Tokens: []InputToken{
 			{Input: "STOP LOSS", TokenType: TOKEN_INSTRUCTION},
 			{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 			{Input: maybeFloat, TokenType: TOKEN_EXPRESSION},
@@ -284,21 +446,256 @@ func (si instrStopLoss) apply(rawInput string, sto *SignalTranspilerOutput) (sig
 	}, true
 }
 
+type instrTrailingStop struct{}
+
+func (si instrTrailingStop) name() string { return instrNameTrailing }
+
+// apply records a stop loss that follows the price at a distance, e.g. 2% below the highest price since entering a
+// LONG. The signal checker doesn't know about it, so signalrunner applies it to the checked candlesticks.
+func (si instrTrailingStop) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
+	upRawInput := strings.ToUpper(rawInput)
+	result := rxTrailingStop.FindStringSubmatch(upRawInput)
+	if len(result) == 0 {
+		return InstructionResult{}, false
+	}
+	if sto.TrailingStopPercentage != 0 {
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrTrailingStopAlreadySupplied, rawInput),
+			Tokens: []InputToken{
+				{Input: rawInput, TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	fl, err := strconv.ParseFloat(result[4], 64)
+	if err != nil || fl <= 0 || fl >= 100 {
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v%%], e.g. TRAILING STOP: 2%%", ErrInvalidTrailingStop, result[4]),
+			Tokens: []InputToken{
+				{Input: "TRAILING STOP", TokenType: TOKEN_INSTRUCTION},
+				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+				{Input: result[4] + "%", TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	sto.TrailingStopPercentage = common.JsonFloat64(fl)
+	return InstructionResult{
+		Tokens: []InputToken{
+			{Input: "TRAILING STOP", TokenType: TOKEN_INSTRUCTION},
+			{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+			{Input: fmt.Sprintf("%v%%", fl), TokenType: TOKEN_EXPRESSION},
+		},
+	}, true
+}
+
+type instrBreakEven struct{}
+
+func (si instrBreakEven) name() string { return instrNameBreakEven }
+
+// apply records that the stop loss moves to the entry price once a take profit is reached. Like the trailing stop,
+// signalrunner applies it.
+func (si instrBreakEven) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
+	upRawInput := strings.ToUpper(rawInput)
+	result := rxBreakEven.FindStringSubmatch(upRawInput)
+	if len(result) == 0 {
+		return InstructionResult{}, false
+	}
+	if sto.BreakEvenAfterTakeProfit != 0 {
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrBreakEvenAlreadySupplied, rawInput),
+			Tokens: []InputToken{
+				{Input: rawInput, TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	n, err := strconv.Atoi(result[6])
+	if err != nil || n < 1 {
+		return InstructionResult{
+			Err: fmt.Errorf("%w with content %v", ErrMalformedInteger, result[6]),
+			Tokens: []InputToken{
+				{Input: "BREAK EVEN AFTER TP", TokenType: TOKEN_INSTRUCTION},
+				{Input: result[6], TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	sto.BreakEvenAfterTakeProfit = n
+	return InstructionResult{
+		Tokens: []InputToken{
+			{Input: "BREAK EVEN AFTER TP", TokenType: TOKEN_INSTRUCTION},
+			{Input: strconv.Itoa(n), TokenType: TOKEN_EXPRESSION},
+		},
+	}, true
+}
+
+type instrLeverage struct{}
+
+func (si instrLeverage) name() string { return instrNameLeverage }
+
+// apply records the leverage of a futures signal, e.g. "LEVERAGE: 10X", optionally with its margin mode, e.g.
+// "LEVERAGE: CROSS 20X". The signal checker doesn't know about it, so signalrunner applies it to the check's result.
+func (si instrLeverage) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
+	upRawInput := strings.ToUpper(rawInput)
+	result := rxLeverage.FindStringSubmatch(upRawInput)
+	if len(result) == 0 {
+		return InstructionResult{}, false
+	}
+	if sto.Leverage != 0 {
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrLeverageAlreadySupplied, rawInput),
+			Tokens: []InputToken{
+				{Input: rawInput, TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	if result[2] != "" && sto.MarginMode != "" {
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrMarginModeAlreadySupplied, rawInput),
+			Tokens: []InputToken{
+				{Input: rawInput, TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	tokens := []InputToken{
+		{Input: "LEVERAGE", TokenType: TOKEN_INSTRUCTION},
+		{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+	}
+	if result[2] != "" {
+		tokens = append(tokens, InputToken{Input: result[2], TokenType: TOKEN_EXPRESSION}, InputToken{Input: " ", TokenType: TOKEN_PUNCTUATION})
+	}
+	fl, err := strconv.ParseFloat(result[3], 64)
+	if err != nil || fl < 1 || fl > maxLeverage {
+		return InstructionResult{
+			Err:    fmt.Errorf("%w [%vX], it should be between 1X and %vX", ErrInvalidLeverage, result[3], maxLeverage),
+			Tokens: append(tokens, InputToken{Input: result[3] + "X", TokenType: TOKEN_ERROR}),
+		}, true
+	}
+	sto.Leverage = common.JsonFloat64(fl)
+	if result[2] != "" {
+		sto.MarginMode = strings.ToLower(result[2])
+	}
+	return InstructionResult{
+		Tokens: append(tokens, InputToken{Input: fmt.Sprintf("%vX", fl), TokenType: TOKEN_EXPRESSION}),
+	}, true
+}
+
+type instrMarginMode struct{}
+
+func (si instrMarginMode) name() string { return instrNameMarginMode }
+
+// apply records the margin mode of a futures signal: CROSS or ISOLATED.
+func (si instrMarginMode) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
+	upRawInput := strings.ToUpper(rawInput)
+	result := rxMarginMode.FindStringSubmatch(upRawInput)
+	if len(result) == 0 {
+		return InstructionResult{}, false
+	}
+	if sto.MarginMode != "" {
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrMarginModeAlreadySupplied, rawInput),
+			Tokens: []InputToken{
+				{Input: rawInput, TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	sto.MarginMode = strings.ToLower(result[3])
+	return InstructionResult{
+		Tokens: []InputToken{
+			{Input: "MARGIN MODE", TokenType: TOKEN_INSTRUCTION},
+			{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+			{Input: result[3], TokenType: TOKEN_EXPRESSION},
+		},
+	}, true
+}
+
+type instrTakeProfitPercentage struct{}
+
+func (si instrTakeProfitPercentage) name() string { return instrNameTakeProfit }
+
+// apply records take profits given as percentages away from the entry. They are resolved to prices later, once the
+// enter range and direction are known.
+func (si instrTakeProfitPercentage) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
+	upRawInput := strings.ToUpper(rawInput)
+	result := rxTakeProfitPct.FindStringSubmatch(upRawInput)
+	if len(result) == 0 {
+		return InstructionResult{}, false
+	}
+	percentages := []float64{}
+	for _, match := range rxPercentage.FindAllStringSubmatch(result[2], -1) {
+		fl, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return InstructionResult{
+				Err: fmt.Errorf("%w with content %v", ErrMalformedFloat, result[2]),
+				Tokens: []InputToken{
+					{Input: "TAKE PROFIT", TokenType: TOKEN_INSTRUCTION},
+					{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+					{Input: result[2], TokenType: TOKEN_ERROR},
+				},
+			}, true
+		}
+		percentages = append(percentages, fl)
+		sto.TakeProfitPercentages = append(sto.TakeProfitPercentages, common.JsonFloat64(fl))
+	}
+	sto.pendingPercentages = true
+	return InstructionResult{
+		Tokens:      percentageTokens("TAKE PROFIT", percentages, nil),
+		percentages: percentages,
+	}, true
+}
+
+type instrStopLossPercentage struct{}
+
+func (si instrStopLossPercentage) name() string { return instrNameStopLoss }
+
+// apply records a stop loss given as a percentage away from the entry, like instrTakeProfitPercentage.
+func (si instrStopLossPercentage) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
+	upRawInput := strings.ToUpper(rawInput)
+	result := rxStopLossPct.FindStringSubmatch(upRawInput)
+	if len(result) == 0 {
+		return InstructionResult{}, false
+	}
+	if sto.SignalInput.StopLoss != common.JsonFloat64(0.0) || sto.StopLossPercentage != 0 {
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrStopLossAlreadySupplied, rawInput),
+			Tokens: []InputToken{
+				{Input: rawInput, TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	fl, err := strconv.ParseFloat(strings.TrimLeft(result[2], "+-"), 64)
+	if err != nil {
+		return InstructionResult{
+			Err: fmt.Errorf("%w with content %v", ErrMalformedFloat, result[2]),
+			Tokens: []InputToken{
+				{Input: "STOP LOSS", TokenType: TOKEN_INSTRUCTION},
+				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+				{Input: result[2], TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	sto.StopLossPercentage = common.JsonFloat64(fl)
+	sto.pendingPercentages = true
+	return InstructionResult{
+		Tokens:      percentageTokens("STOP LOSS", []float64{fl}, nil),
+		percentages: []float64{fl},
+	}, true
+}
+
 type instrExchange struct{}
 
-func (si instrExchange) apply(rawInput string, sto *SignalTranspilerOutput) (signalInstruction, bool) {
+func (si instrExchange) name() string { return instrNameExchange }
+
+func (si instrExchange) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
 	upRawInput := strings.ToUpper(rawInput)
 	result := rxExchange.FindStringSubmatch(upRawInput)
 	if len(result) == 0 {
-		return signalInstruction{}, false
+		return InstructionResult{}, false
 	}
 	if result[1] == "" && !isSInSS(result[2], exchangeList) {
-		return signalInstruction{}, false
+		return InstructionResult{}, false
 	}
-	if result[1] != "" && !isSInSS(result[2], supportedExchangeList) {
-		return signalInstruction{
-			err: fmt.Errorf("%w for exchange [%v]", errUnsupportedExchange, result[2]),
-			tokenizedInput: []inputToken{
+	if result[1] != "" && !isSInSS(result[2], t.supportedExchangesOrDefault()) {
+		return InstructionResult{
+			Err: fmt.Errorf("%w for exchange [%v]", ErrUnsupportedExchange, result[2]),
+			Tokens: []InputToken{
 				{Input: "EXCHANGE", TokenType: TOKEN_INSTRUCTION},
 				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 				{Input: result[2], TokenType: TOKEN_ERROR},
@@ -306,16 +703,16 @@ func (si instrExchange) apply(rawInput string, sto *SignalTranspilerOutput) (sig
 		}, true
 	}
 	if sto.SignalInput.Exchange != "" {
-		return signalInstruction{
-			err: fmt.Errorf("%w [%v]", errExchangeAlreadySupplied, rawInput),
-			tokenizedInput: []inputToken{
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrExchangeAlreadySupplied, rawInput),
+			Tokens: []InputToken{
 				{Input: rawInput, TokenType: TOKEN_ERROR},
 			},
 		}, true
 	}
 	sto.SignalInput.Exchange = strings.ToLower(result[2])
-	return signalInstruction{
-		tokenizedInput: []inputToken{
+	return InstructionResult{
+		Tokens: []InputToken{
 			{Input: "EXCHANGE", TokenType: TOKEN_INSTRUCTION},
 			{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 			{Input: result[2], TokenType: TOKEN_EXPRESSION},
@@ -323,58 +720,161 @@ func (si instrExchange) apply(rawInput string, sto *SignalTranspilerOutput) (sig
 	}, true
 }
 
+type instrTimezone struct{}
+
+func (si instrTimezone) name() string { return instrNameTimezone }
+
+func (si instrTimezone) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
+	// N.B. matched as typed rather than uppercased, because time zone names are case-sensitive
+	result := rxTimezone.FindStringSubmatch(rawInput)
+	if len(result) == 0 {
+		return InstructionResult{}, false
+	}
+	if sto.location != nil {
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrTimezoneAlreadySupplied, rawInput),
+			Tokens: []InputToken{
+				{Input: rawInput, TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	location, ok, _ := parseZone(result[2])
+	if !ok {
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrUnsupportedTimezone, result[2]),
+			Tokens: []InputToken{
+				{Input: "TIMEZONE", TokenType: TOKEN_INSTRUCTION},
+				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+				{Input: result[2], TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	sto.location = location
+	return InstructionResult{
+		Tokens: []InputToken{
+			{Input: "TIMEZONE", TokenType: TOKEN_INSTRUCTION},
+			{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+			{Input: result[2], TokenType: TOKEN_EXPRESSION},
+		},
+	}, true
+}
+
+type instrStrict struct{}
+
+func (si instrStrict) name() string { return instrNameStrict }
+
+// apply turns on strict mode for this signal, like WithStrictMode does for every signal.
+func (si instrStrict) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
+	if !rxStrict.MatchString(strings.ToUpper(rawInput)) {
+		return InstructionResult{}, false
+	}
+	sto.isStrict = true
+	return InstructionResult{
+		Tokens: []InputToken{
+			{Input: "STRICT", TokenType: TOKEN_INSTRUCTION},
+		},
+	}, true
+}
+
+type instrDialect struct{}
+
+func (si instrDialect) name() string { return instrNameDialect }
+
+// apply only validates the dialect's name: the dialect is picked before the signal is split into instructions.
+func (si instrDialect) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
+	result := rxDialect.FindStringSubmatch(strings.ToUpper(rawInput))
+	if len(result) == 0 {
+		return InstructionResult{}, false
+	}
+	if _, ok := LookupDialect(result[1]); !ok {
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v], use one of %v", ErrUnsupportedDialect, result[1], strings.Join(DialectNames(), ", ")),
+			Tokens: []InputToken{
+				{Input: "DIALECT", TokenType: TOKEN_INSTRUCTION},
+				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+				{Input: result[1], TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	return InstructionResult{
+		Tokens: []InputToken{
+			{Input: "DIALECT", TokenType: TOKEN_INSTRUCTION},
+			{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+			{Input: strings.ToLower(result[1]), TokenType: TOKEN_EXPRESSION},
+		},
+	}, true
+}
+
 type instrInitialISO8601 struct{}
 
-func (si instrInitialISO8601) apply(rawInput string, sto *SignalTranspilerOutput) (signalInstruction, bool) {
-	upRawInput := strings.ToUpper(rawInput)
-	result := rxInitialISO8601.FindStringSubmatch(upRawInput)
+func (si instrInitialISO8601) name() string { return instrNameStartAt }
 
-	// TODO support other formats
-	iso8601, err := tryParseDate(result[2])
+func (si instrInitialISO8601) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
+	// N.B. matched as typed rather than uppercased, because time zone names are case-sensitive
+	result := rxInitialISO8601.FindStringSubmatch(rawInput)
+	if len(result) == 0 {
+		return InstructionResult{}, false
+	}
+	parsed, err := t.parseDate(result[2], sto.location)
 	if result[1] == "" && err != nil {
-		return signalInstruction{}, false
+		return InstructionResult{}, false
 	}
 	if sto.SignalInput.InitialISO8601 != "" {
-		return signalInstruction{
-			err: fmt.Errorf("%w [%v]", errInitialISO8601AlreadySupplied, rawInput),
-			tokenizedInput: []inputToken{
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrInitialISO8601AlreadySupplied, rawInput),
+			Tokens: []InputToken{
 				{Input: rawInput, TokenType: TOKEN_ERROR},
 			},
 		}, true
 	}
 	if result[1] != "" && err != nil {
-		return signalInstruction{
-			err: fmt.Errorf("%w for datetime [%v]", errUnsupportedDateTimeFormat, result[2]),
-			tokenizedInput: []inputToken{
+		return InstructionResult{
+			Err: fmt.Errorf("%w for datetime [%v]", ErrUnsupportedDateTimeFormat, result[2]),
+			Tokens: []InputToken{
 				{Input: "START AT", TokenType: TOKEN_INSTRUCTION},
 				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 				{Input: result[2], TokenType: TOKEN_ERROR},
 			},
 		}, true
 	}
+	// An ambiguous date is left unset rather than guessed, and the warning says how to write it
+	if parsed.Date.IsZero() {
+		return InstructionResult{
+			Tokens: []InputToken{
+				{Input: "START AT", TokenType: TOKEN_INSTRUCTION},
+				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+				{Input: result[2], TokenType: TOKEN_EXPRESSION},
+			},
+			Warning: parsed.Warning,
+		}, true
+	}
+	iso8601 := parsed.Date.UTC().Format(time.RFC3339)
 	sto.SignalInput.InitialISO8601 = common.ISO8601(iso8601)
-	return signalInstruction{
-		tokenizedInput: []inputToken{
+	return InstructionResult{
+		Tokens: []InputToken{
 			{Input: "START AT", TokenType: TOKEN_INSTRUCTION},
 			{Input: ": ", TokenType: TOKEN_PUNCTUATION},
-			{Input: result[2], TokenType: TOKEN_EXPRESSION},
+			{Input: iso8601, TokenType: TOKEN_EXPRESSION},
 		},
+		Warning: parsed.Warning,
 	}, true
 }
 
 type instrIsShort struct{}
 
-func (si instrIsShort) apply(rawInput string, sto *SignalTranspilerOutput) (signalInstruction, bool) {
+func (si instrIsShort) name() string { return instrNameDirection }
+
+func (si instrIsShort) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
 	upRawInput := strings.ToUpper(rawInput)
 	result := rxIsShort.FindStringSubmatch(upRawInput)
 
 	if len(result) == 0 {
-		return signalInstruction{}, false
+		return InstructionResult{}, false
 	}
 	if sto.isShortSet {
-		return signalInstruction{
-			err: fmt.Errorf("%w [%v]", errIsShortAlreadySupplied, rawInput),
-			tokenizedInput: []inputToken{
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrIsShortAlreadySupplied, rawInput),
+			Tokens: []InputToken{
 				{Input: rawInput, TokenType: TOKEN_ERROR},
 			},
 		}, true
@@ -383,14 +883,14 @@ func (si instrIsShort) apply(rawInput string, sto *SignalTranspilerOutput) (sign
 
 	if result[1] == "SHORT" {
 		sto.SignalInput.IsShort = true
-		return signalInstruction{
-			tokenizedInput: []inputToken{
+		return InstructionResult{
+			Tokens: []InputToken{
 				{Input: "SHORT", TokenType: TOKEN_INSTRUCTION},
 			},
 		}, true
 	}
-	return signalInstruction{
-		tokenizedInput: []inputToken{
+	return InstructionResult{
+		Tokens: []InputToken{
 			{Input: "LONG", TokenType: TOKEN_INSTRUCTION},
 		},
 	}, true
@@ -398,58 +898,117 @@ func (si instrIsShort) apply(rawInput string, sto *SignalTranspilerOutput) (sign
 
 type instrInvalidate struct{}
 
-func (si instrInvalidate) apply(rawInput string, sto *SignalTranspilerOutput) (signalInstruction, bool) {
+func (si instrInvalidate) name() string { return instrNameTimeout }
+
+func (si instrInvalidate) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
 	upRawInput := strings.ToUpper(rawInput)
 	result := rxInvalidateISO8601.FindStringSubmatch(upRawInput)
 
 	if len(result) == 0 {
-		return signalInstruction{}, false
+		return InstructionResult{}, false
 	}
-	if sto.SignalInput.InvalidateAfterSeconds > 0 {
-		return signalInstruction{
-			err: fmt.Errorf("%w [%v]", errInvalidateAfterDaysAlreadySupplied, rawInput),
-			tokenizedInput: []inputToken{
+	if sto.SignalInput.InvalidateAfterSeconds > 0 || !sto.invalidateAt.IsZero() {
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrInvalidateAfterDaysAlreadySupplied, rawInput),
+			Tokens: []InputToken{
 				{Input: rawInput, TokenType: TOKEN_ERROR},
 			},
 		}, true
 	}
 
-	days, err := strconv.Atoi(result[4])
+	timeout, err := parseTimeout(result[4])
 	if err != nil {
-		return signalInstruction{
-			err: fmt.Errorf("%w [%v]", errMalformedInteger, result[4]),
-			tokenizedInput: []inputToken{
+		return InstructionResult{
+			Err: err,
+			Tokens: []InputToken{
 				{Input: "TIMEOUT AFTER", TokenType: TOKEN_INSTRUCTION},
 				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
 				{Input: result[4], TokenType: TOKEN_ERROR},
-				{Input: " DAYS", TokenType: TOKEN_EXPRESSION},
 			},
 		}, true
 	}
-	if days > 7 {
-		return signalInstruction{
-			err: fmt.Errorf("%w [%v]", errMaximumInvalidation7Days, result[4]),
-			tokenizedInput: []inputToken{
+	if timeout > t.maxTimeoutOrDefault() {
+		return InstructionResult{
+			Err: fmt.Errorf("%w of %v [%v]", ErrMaximumTimeout, formatTimeout(t.maxTimeoutOrDefault()), result[4]),
+			Tokens: []InputToken{
 				{Input: "TIMEOUT AFTER", TokenType: TOKEN_INSTRUCTION},
 				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
-				{Input: fmt.Sprintf("%v", days), TokenType: TOKEN_ERROR},
-				{Input: " DAYS", TokenType: TOKEN_EXPRESSION},
+				{Input: formatTimeout(timeout), TokenType: TOKEN_ERROR},
 			},
 		}, true
 	}
 
-	sto.SignalInput.InvalidateAfterSeconds = days * 86400
+	sto.SignalInput.InvalidateAfterSeconds = int(timeout / time.Second)
 
-	return signalInstruction{
-		tokenizedInput: []inputToken{
+	return InstructionResult{
+		Tokens: []InputToken{
 			{Input: "TIMEOUT AFTER", TokenType: TOKEN_INSTRUCTION},
 			{Input: ": ", TokenType: TOKEN_PUNCTUATION},
-			{Input: fmt.Sprintf("%v", days), TokenType: TOKEN_EXPRESSION},
-			{Input: " DAYS", TokenType: TOKEN_EXPRESSION},
+			{Input: formatTimeout(timeout), TokenType: TOKEN_EXPRESSION},
+		},
+	}, true
+}
+
+type instrInvalidateAt struct{}
+
+func (si instrInvalidateAt) name() string { return instrNameTimeout }
+
+// apply records an absolute timeout. It's turned into a timeout relative to START AT once all lines are applied.
+func (si instrInvalidateAt) apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
+	// N.B. matched as typed rather than uppercased, because time zone names are case-sensitive
+	result := rxInvalidateAt.FindStringSubmatch(rawInput)
+	if len(result) == 0 {
+		return InstructionResult{}, false
+	}
+	if sto.SignalInput.InvalidateAfterSeconds > 0 || !sto.invalidateAt.IsZero() {
+		return InstructionResult{
+			Err: fmt.Errorf("%w [%v]", ErrInvalidateAfterDaysAlreadySupplied, rawInput),
+			Tokens: []InputToken{
+				{Input: rawInput, TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	parsed, err := t.parseDate(result[2], sto.location)
+	if err != nil {
+		return InstructionResult{
+			Err: fmt.Errorf("%w for datetime [%v]", ErrUnsupportedDateTimeFormat, result[2]),
+			Tokens: []InputToken{
+				{Input: "INVALIDATE AT", TokenType: TOKEN_INSTRUCTION},
+				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+				{Input: result[2], TokenType: TOKEN_ERROR},
+			},
+		}, true
+	}
+	if parsed.Date.IsZero() {
+		return InstructionResult{
+			Tokens: []InputToken{
+				{Input: "INVALIDATE AT", TokenType: TOKEN_INSTRUCTION},
+				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+				{Input: result[2], TokenType: TOKEN_EXPRESSION},
+			},
+			Warning: parsed.Warning,
+		}, true
+	}
+	sto.invalidateAt = parsed.Date
+	return InstructionResult{
+		Tokens: []InputToken{
+			{Input: "INVALIDATE AT", TokenType: TOKEN_INSTRUCTION},
+			{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+			{Input: parsed.Date.UTC().Format(time.RFC3339), TokenType: TOKEN_EXPRESSION},
 		},
+		Warning: parsed.Warning,
 	}, true
 }
 
+// trailingComment returns the "// ..." comment at the end of a line as typed, or "" if there is none.
+func trailingComment(rawInput string) string {
+	i := strings.Index(rawInput, "//")
+	if i == -1 {
+		return ""
+	}
+	return strings.TrimSpace(rawInput[i:])
+}
+
 func isSInSS(s string, ss []string) bool {
 	for _, si := range ss {
 		if s == si {
@@ -466,7 +1025,7 @@ func extractFloatSequence(fls string) ([]float64, error) {
 	containsDash := strings.Contains(fls, "-")
 	containsAnd := strings.Contains(fls, "AND")
 	if (containsComma && containsDash) || (containsComma && containsAnd) || (containsDash && containsAnd) {
-		return result, errMixesSeparators
+		return result, ErrMixesSeparators
 	}
 	flss := []string{fls}
 	if containsComma {
@@ -482,31 +1041,10 @@ func extractFloatSequence(fls string) ([]float64, error) {
 		for _, fl := range strings.Fields(fls) {
 			f, err := strconv.ParseFloat(fl, 64)
 			if err != nil {
-				return result, errMalformedFloat
+				return result, ErrMalformedFloat
 			}
 			result = append(result, f)
 		}
 	}
 	return result, nil
 }
-
-func tryParseDate(s string) (common.ISO8601, error) {
-	formats := []string{
-		time.RFC3339,
-		"2006-01-02",
-	}
-	var (
-		t   time.Time
-		err error
-	)
-	for _, format := range formats {
-		t, err = time.Parse(format, s)
-		if err == nil {
-			break
-		}
-	}
-	if err != nil {
-		return "", err
-	}
-	return common.ISO8601(t.Format(time.RFC3339)), nil
-}
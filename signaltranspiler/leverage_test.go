@@ -0,0 +1,160 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+func TestLeverage(t *testing.T) {
+	ts := []struct {
+		name               string
+		lines              string
+		expectedLeverage   common.JsonFloat64
+		expectedMarginMode string
+		expectedLines      []string
+		expectedErr        error
+	}{
+		{name: "leverage", lines: "LEVERAGE: 20X", expectedLeverage: 20, expectedLines: []string{"LEVERAGE: 20X"}},
+		{name: "with margin mode", lines: "leverage: cross 20x", expectedLeverage: 20, expectedMarginMode: "cross", expectedLines: []string{"LEVERAGE: CROSS 20X"}},
+		{name: "short and in parentheses", lines: "LEV (10x)", expectedLeverage: 10, expectedLines: []string{"LEVERAGE: 10X"}},
+		{
+			name:               "margin mode on its own line",
+			lines:              "LEVERAGE: 10X\nISOLATED MARGIN",
+			expectedLeverage:   10,
+			expectedMarginMode: "isolated",
+			expectedLines:      []string{"LEVERAGE: 10X", "MARGIN MODE: ISOLATED"},
+		},
+		{name: "over the maximum", lines: "LEVERAGE: 200X", expectedErr: ErrInvalidLeverage},
+		{name: "zero", lines: "LEVERAGE: 0X", expectedErr: ErrInvalidLeverage},
+		{name: "twice", lines: "LEVERAGE: 10X\nLEV: 20X", expectedErr: ErrLeverageAlreadySupplied},
+		{name: "margin mode twice", lines: "LEVERAGE: CROSS 10X\nISOLATED", expectedErr: ErrMarginModeAlreadySupplied},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := NewSignalTranspiler().Transpile("BTC/USDT BINANCEUSDMFUTURES\n" + tc.lines + "\nENTER: 30000 - 31000\nSTART AT: 2021-06-22T15:21:00Z")
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if output.Leverage != tc.expectedLeverage || output.MarginMode != tc.expectedMarginMode {
+				t.Errorf("expected %vX %q, got %vX %q", tc.expectedLeverage, tc.expectedMarginMode, output.Leverage, output.MarginMode)
+			}
+			lines := []string{}
+			for _, line := range output.TokenizedInput[1 : 1+len(tc.expectedLines)] {
+				lines = append(lines, joinTokens(line))
+			}
+			if !reflect.DeepEqual(lines, tc.expectedLines) {
+				t.Errorf("expected %q, got %q", tc.expectedLines, lines)
+			}
+		})
+	}
+}
+
+func TestLeverageExchange(t *testing.T) {
+	ts := []struct {
+		name             string
+		input            string
+		expectedExchange string
+		expectedErr      error
+	}{
+		{name: "futures exchange", input: "BTC/USDT BINANCEUSDMFUTURES\nLEVERAGE: 10X", expectedExchange: common.BINANCE_USDM_FUTURES},
+		{name: "futures exchange inferred from the leverage", input: "BTC/USDT\nLEVERAGE: 10X", expectedExchange: common.BINANCE_USDM_FUTURES},
+		{name: "futures exchange inferred from the margin mode", input: "BTC/USDT\nCROSS", expectedExchange: common.BINANCE_USDM_FUTURES},
+		{name: "spot exchange without leverage", input: "BTC/USDT KRAKEN", expectedExchange: "kraken"},
+		{name: "leverage on a spot exchange", input: "BTC/USDT KRAKEN\nLEVERAGE: 10X", expectedErr: ErrLeverageRequiresFutures},
+		{name: "margin mode on a spot exchange", input: "BTC/USDT KRAKEN\nCROSS", expectedErr: ErrLeverageRequiresFutures},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := NewSignalTranspiler().Transpile(tc.input + "\nENTER: 30000 - 31000\nSTART AT: 2021-06-22T15:21:00Z")
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err == nil && output.SignalInput.Exchange != tc.expectedExchange {
+				t.Errorf("expected %v, got %v", tc.expectedExchange, output.SignalInput.Exchange)
+			}
+		})
+	}
+}
+
+func TestStopLossBeyondLiquidation(t *testing.T) {
+	ts := []struct {
+		name            string
+		input           string
+		disabledWarning string
+		expected        []Diagnostic
+	}{
+		{
+			name:  "long, against the highest entry",
+			input: "LEVERAGE: CROSS 20X\nENTER: 30000 - 31000\nSL: 29000\nTP: 32000",
+			expected: []Diagnostic{{Code: WARNING_STOP_LOSS_BEYOND_LIQUIDATION, Severity: SEVERITY_WARNING, Line: 3, ColumnStart: 0, ColumnEnd: 9,
+				Message: "stop loss is beyond the liquidation price of 29450 at 20X"}},
+		},
+		{
+			name:     "long, before the liquidation price",
+			input:    "LEVERAGE: 10X\nENTER: 30000 - 31000\nSL: 29000\nTP: 32000",
+			expected: []Diagnostic{},
+		},
+		{
+			name:  "short, against the lowest entry",
+			input: "SHORT\nLEVERAGE: 50X\nENTER: 30000 - 31000\nSL: 31700\nTP: 29000",
+			expected: []Diagnostic{{Code: WARNING_STOP_LOSS_BEYOND_LIQUIDATION, Severity: SEVERITY_WARNING, Line: 4, ColumnStart: 0, ColumnEnd: 9,
+				Message: "stop loss is beyond the liquidation price of 30600 at 50X"}},
+		},
+		{
+			name:  "percentage stop loss",
+			input: "LEVERAGE: 20X\nENTER: IMMEDIATELY\nSL: 6%\nTP: 5%",
+			expected: []Diagnostic{{Code: WARNING_STOP_LOSS_BEYOND_LIQUIDATION, Severity: SEVERITY_WARNING, Line: 3, ColumnStart: 0, ColumnEnd: 6,
+				Message: "stop loss is beyond the liquidation price: 6% away from entry, but 20X is liquidated 5.00% away"}},
+		},
+		{
+			name:            "with the warning disabled",
+			input:           "LEVERAGE: CROSS 20X\nENTER: 30000 - 31000\nSL: 29000\nTP: 32000",
+			disabledWarning: WARNING_STOP_LOSS_BEYOND_LIQUIDATION,
+			expected:        []Diagnostic{},
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			transpiler := NewSignalTranspiler()
+			if tc.disabledWarning != "" {
+				transpiler.DisableWarningRule(tc.disabledWarning)
+			}
+			output, err := transpiler.Transpile("BTC/USDT BINANCEUSDMFUTURES\n" + tc.input + "\nSTART AT: 2021-06-22T15:21:00Z")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(output.Diagnostics, tc.expected) {
+				t.Errorf("expected %+v\ngot      %+v", tc.expected, output.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestLiquidationPrice(t *testing.T) {
+	ts := []struct {
+		name     string
+		entry    float64
+		leverage float64
+		isShort  bool
+		expected float64
+	}{
+		{name: "long", entry: 100, leverage: 10, expected: 90},
+		{name: "short", entry: 100, leverage: 10, isShort: true, expected: 110},
+		{name: "long without leverage", entry: 100, leverage: 1, expected: 0},
+		{name: "short at the maximum leverage", entry: 100, leverage: 125, isShort: true, expected: 100.8},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := LiquidationPrice(tc.entry, tc.leverage, tc.isShort); math.Abs(actual-tc.expected) > 1e-9 {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package signaltranspiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marianogappa/hts/marketcatalog"
+)
+
+// UnlistedMarketError is the warning, or the error in strict mode, for a market that the market catalog says the
+// exchange doesn't list. It matches ErrUnlistedMarket with errors.Is.
+type UnlistedMarketError struct {
+	Market     string
+	Exchange   string
+	Suggestion string
+}
+
+func (e *UnlistedMarketError) Error() string {
+	msg := fmt.Sprintf("%v: %v on %v", ErrUnlistedMarket, e.Market, strings.ToUpper(e.Exchange))
+	if e.Suggestion != "" {
+		msg += ", did you mean " + e.Suggestion + "?"
+	}
+	return msg
+}
+
+func (e *UnlistedMarketError) Is(target error) bool {
+	return target == ErrUnlistedMarket
+}
+
+// checkUnlistedMarket checks the market against the market catalog. Exchanges the catalog doesn't know accept every
+// market. The warning is about the market even if the exchange was given, since the market is usually what to fix.
+func checkUnlistedMarket(t SignalTranspiler, sto SignalTranspilerOutput) (string, error) {
+	in := sto.SignalInput
+	if t.marketCatalog == nil || in.BaseAsset == "" || in.Exchange == "" || t.marketCatalog.HasMarket(in.Exchange, in.BaseAsset, in.QuoteAsset) {
+		return "", nil
+	}
+	return instrNameMarket, &UnlistedMarketError{
+		Market:     in.BaseAsset + "/" + in.QuoteAsset,
+		Exchange:   in.Exchange,
+		Suggestion: t.marketCatalog.Suggest(in.Exchange, in.BaseAsset, in.QuoteAsset),
+	}
+}
+
+// WithMarketCatalog sets the catalog that markets are checked against. By default it's the catalog embedded in the
+// binary (see marketcatalog.Default), and nil checks nothing. Since a snapshot can't list every market, a market that
+// it doesn't list is a warning (WARNING_UNLISTED_MARKET), except in strict mode, where it's an error.
+func WithMarketCatalog(catalog *marketcatalog.Catalog) Option {
+	return func(t *SignalTranspiler) {
+		t.marketCatalog = catalog
+	}
+}
@@ -0,0 +1,132 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/marianogappa/hts/marketcatalog"
+)
+
+func TestUnlistedMarket(t *testing.T) {
+	catalog, err := marketcatalog.Load(strings.NewReader(`{"exchanges": {"binance": ["BTC/USDT", "ETH/USDT"], "kraken": ["BTC/USD"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := []struct {
+		name            string
+		input           string
+		opts            []Option
+		disabledWarning string
+		expected        []Diagnostic
+	}{
+		{
+			name:     "listed",
+			input:    "BTC/USDT",
+			expected: []Diagnostic{},
+		},
+		{
+			name:  "unlisted on the inferred exchange",
+			input: "RVN/USDT",
+			expected: []Diagnostic{{Code: WARNING_UNLISTED_MARKET, Severity: SEVERITY_WARNING, Line: 0, ColumnStart: 0, ColumnEnd: 8,
+				Message: "market not listed on the exchange: RVN/USDT on BINANCE"}},
+		},
+		{
+			name:  "unlisted on the given exchange, with a suggestion",
+			input: "BTCX/USDQ\nEXCHANGE: KRAKEN",
+			expected: []Diagnostic{{Code: WARNING_UNLISTED_MARKET, Severity: SEVERITY_WARNING, Line: 0, ColumnStart: 0, ColumnEnd: 9,
+				Message: "market not listed on the exchange: BTCX/USDQ on KRAKEN, did you mean BTC/USD?", SuggestedFix: "MARKET: BTC/USD"}},
+		},
+		{
+			name:     "exchange that the catalog doesn't know",
+			input:    "RVN/USDT\nEXCHANGE: KUCOIN",
+			expected: []Diagnostic{},
+		},
+		{
+			name:            "unlisted with the warning disabled",
+			input:           "RVN/USDT",
+			disabledWarning: WARNING_UNLISTED_MARKET,
+			expected:        []Diagnostic{},
+		},
+		{
+			name:     "unlisted without a catalog",
+			input:    "RVN/USDT",
+			opts:     []Option{WithMarketCatalog(nil)},
+			expected: []Diagnostic{},
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			transpiler := NewSignalTranspiler(append([]Option{WithMarketCatalog(catalog)}, tc.opts...)...)
+			if tc.disabledWarning != "" {
+				transpiler.DisableWarningRule(tc.disabledWarning)
+			}
+			output, err := transpiler.Transpile(tc.input + "\nENTER: 100 - 110\nTP: 120\nSL: 95\nSTART AT: 2021-06-22T15:21:00Z")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(output.Diagnostics, tc.expected) {
+				t.Errorf("expected %+v\ngot      %+v", tc.expected, output.Diagnostics)
+			}
+		})
+	}
+}
+
+// TestUnlistedMarketDefaultCatalog checks that markets missing from the embedded snapshot, which can't list every
+// market, still transpile.
+func TestUnlistedMarketDefaultCatalog(t *testing.T) {
+	for _, market := range []string{"RVN/USDT", "BTC/TRY", "BTCUP/USDT", "SHIB1000/USDT BINANCEUSDMFUTURES"} {
+		t.Run(market, func(t *testing.T) {
+			output, err := NewSignalTranspiler().Transpile(market + "\nENTER: 100 - 110\nTP: 120\nSL: 95\nSTART AT: 2021-06-22T15:21:00Z")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(output.Diagnostics) != 1 || output.Diagnostics[0].Code != WARNING_UNLISTED_MARKET {
+				t.Errorf("expected an %v warning, got %+v", WARNING_UNLISTED_MARKET, output.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestUnlistedMarketStrict(t *testing.T) {
+	catalog, err := marketcatalog.Load(strings.NewReader(`{"exchanges": {"kraken": ["BTC/USD"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := []struct {
+		name            string
+		input           string
+		opts            []Option
+		disabledWarning string
+		expectedErr     error
+	}{
+		{name: "strict mode option", input: "MARKET: BTCX/USDQ", opts: []Option{WithStrictMode(true)}, expectedErr: ErrUnlistedMarket},
+		{name: "strict directive", input: "STRICT\nMARKET: BTCX/USDQ", expectedErr: ErrUnlistedMarket},
+		{name: "listed", input: "STRICT\nMARKET: BTC/USD"},
+		{name: "not strict", input: "MARKET: BTCX/USDQ"},
+		{name: "with the warning disabled", input: "STRICT\nMARKET: BTCX/USDQ", disabledWarning: WARNING_UNLISTED_MARKET},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			transpiler := NewSignalTranspiler(append([]Option{WithMarketCatalog(catalog)}, tc.opts...)...)
+			if tc.disabledWarning != "" {
+				transpiler.DisableWarningRule(tc.disabledWarning)
+			}
+			output, err := transpiler.Transpile(tc.input + "\nEXCHANGE: KRAKEN\nLONG\nENTER: 100 - 110\nTP: 120\nSL: 95\nSTART AT: 2021-06-22T15:21:00Z\nTIMEOUT AFTER: 2 DAYS")
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err == nil {
+				return
+			}
+			var unlisted *UnlistedMarketError
+			if !errors.As(err, &unlisted) || unlisted.Suggestion != "BTC/USD" {
+				t.Errorf("expected an *UnlistedMarketError suggesting BTC/USD, got %#v", unlisted)
+			}
+			if len(output.Diagnostics) != 1 || output.Diagnostics[0].Severity != SEVERITY_ERROR || output.Diagnostics[0].SuggestedFix != "MARKET: BTC/USD" {
+				t.Errorf("expected one error with a suggested fix, got %+v", output.Diagnostics)
+			}
+		})
+	}
+}
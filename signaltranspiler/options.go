@@ -0,0 +1,97 @@
+package signaltranspiler
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultExchange is the exchange inferred for signals that don't specify one.
+	DefaultExchange = "binance"
+	// DefaultTimeout is the timeout inferred for signals that don't specify one.
+	DefaultTimeout = 2 * 24 * time.Hour
+	// DefaultMaxTimeout is the longest timeout a signal may have, e.g. TIMEOUT AFTER: 5 WEEKS is an error. Longer
+	// timeouts need WithMaxTimeout, and checking them fetches more candlesticks.
+	DefaultMaxTimeout = 30 * 24 * time.Hour
+)
+
+// Option configures a SignalTranspiler.
+type Option func(*SignalTranspiler)
+
+// WithClock sets the current time, which relative dates like "yesterday 14:00" are relative to.
+func WithClock(now func() time.Time) Option {
+	return func(t *SignalTranspiler) {
+		t.now = now
+	}
+}
+
+// WithDefaultExchange sets the exchange inferred for signals that don't specify one.
+func WithDefaultExchange(exchange string) Option {
+	return func(t *SignalTranspiler) {
+		t.defaultExchange = strings.ToLower(exchange)
+	}
+}
+
+// WithDefaultTimeout sets the timeout inferred for signals that don't specify one. It's rounded up to whole minutes,
+// since that's as precise as TIMEOUT AFTER gets, e.g. 30 seconds infers TIMEOUT AFTER 1 MINUTE.
+func WithDefaultTimeout(timeout time.Duration) Option {
+	return func(t *SignalTranspiler) {
+		t.defaultTimeout = (timeout + time.Minute - 1).Truncate(time.Minute)
+	}
+}
+
+// WithMaxTimeout sets the longest timeout a signal may have, either with TIMEOUT AFTER or with INVALIDATE AT.
+func WithMaxTimeout(max time.Duration) Option {
+	return func(t *SignalTranspiler) {
+		t.maxTimeout = max
+	}
+}
+
+// WithSupportedExchanges sets the exchanges that the EXCHANGE instruction accepts.
+func WithSupportedExchanges(exchanges ...string) Option {
+	return func(t *SignalTranspiler) {
+		t.supportedExchanges = []string{}
+		for _, exchange := range exchanges {
+			t.supportedExchanges = append(t.supportedExchanges, strings.ToUpper(exchange))
+		}
+	}
+}
+
+// WithStrictMode makes every signal strict, as if it had the STRICT directive: nothing is inferred, so every
+// instruction must be in the signal, transpiling stops at the first unrecognized line, and a market that the market
+// catalog doesn't list is an error.
+func WithStrictMode(strict bool) Option {
+	return func(t *SignalTranspiler) {
+		t.strict = strict
+	}
+}
+
+// The ...OrDefault methods make the zero SignalTranspiler behave like NewSignalTranspiler().
+
+func (t SignalTranspiler) defaultExchangeOrDefault() string {
+	if t.defaultExchange == "" {
+		return DefaultExchange
+	}
+	return t.defaultExchange
+}
+
+func (t SignalTranspiler) defaultTimeoutOrDefault() time.Duration {
+	if t.defaultTimeout <= 0 {
+		return DefaultTimeout
+	}
+	return t.defaultTimeout
+}
+
+func (t SignalTranspiler) maxTimeoutOrDefault() time.Duration {
+	if t.maxTimeout <= 0 {
+		return DefaultMaxTimeout
+	}
+	return t.maxTimeout
+}
+
+func (t SignalTranspiler) supportedExchangesOrDefault() []string {
+	if t.supportedExchanges == nil {
+		return supportedExchangeList
+	}
+	return t.supportedExchanges
+}
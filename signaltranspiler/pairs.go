@@ -0,0 +1,84 @@
+package signaltranspiler
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/marianogappa/hts/marketcatalog"
+)
+
+var (
+	rxAsset        = regexp.MustCompile(`^[[:digit:]]*[[:upper:]]{2,}[[:digit:]]*$`)
+	rxHashtagPair  = regexp.MustCompile(`#([[:digit:]]*[[:upper:]]{2,}[[:digit:]]*(?:[/_-][[:upper:]]{2,})?)\b`)
+	rxPairSplitter = regexp.MustCompile(`[/_-]`)
+)
+
+// quoteAssets are the assets that markets are commonly quoted in, to split symbols like "BTCUSDT". Assets that end
+// common words (e.g. TRY, like "ENTRY") are left out, so that those words aren't read as markets.
+var quoteAssets = []string{"USDT", "BUSD", "USDC", "TUSD", "USD", "EUR", "GBP", "BTC", "ETH", "BNB", "DAI"}
+
+// assetAliases are the names that some exchanges give to assets, e.g. Kraken's XBT for BTC.
+var assetAliases = map[string]string{
+	"XBT": "BTC",  // Kraken
+	"XDG": "DOGE", // Kraken
+	"BCC": "BCH",  // Binance, before the BCH rename
+}
+
+// resolvePair returns the base and quote assets of an uppercase symbol, e.g. "BTC/USDT", "BTC_USDT", "1INCH-USDT"
+// or "BTCUSDT", with aliases like XBT replaced by the usual name. ok is false if the symbol isn't a market.
+//
+// A symbol without a separator may split in several ways, e.g. "DOTUSD" is DOT/USD or DO/TUSD. The split that the
+// catalog lists on the exchange wins, then the one with the longest known base (an alias, or an asset that the
+// catalog lists anywhere), then the one with the longest quote. The catalog may be nil.
+func resolvePair(symbol string, catalog *marketcatalog.Catalog, exchange string) (base, quote string, ok bool) {
+	symbol = strings.TrimPrefix(symbol, "#")
+	if parts := rxPairSplitter.Split(symbol, -1); len(parts) == 2 {
+		base, quote = parts[0], parts[1]
+	} else if len(parts) == 1 {
+		bestRank := -1
+		for _, quoteAsset := range quoteAssets {
+			candidate := strings.TrimSuffix(symbol, quoteAsset)
+			if !strings.HasSuffix(symbol, quoteAsset) || !rxAsset.MatchString(candidate) {
+				continue
+			}
+			if rank := rankPair(candidate, quoteAsset, catalog, exchange); rank > bestRank {
+				base, quote, bestRank = candidate, quoteAsset, rank
+			}
+		}
+	}
+	if !rxAsset.MatchString(base) || !rxAsset.MatchString(quote) {
+		return "", "", false
+	}
+	return resolveAsset(base), resolveAsset(quote), true
+}
+
+// rankPair ranks a split of a symbol for resolvePair: higher is likelier, and a listed market outranks any base length.
+// Ties go to the longer quote, which comes first in quoteAssets.
+func rankPair(base, quote string, catalog *marketcatalog.Catalog, exchange string) int {
+	_, isAlias := assetAliases[base]
+	rank := 0
+	if isAlias || (catalog != nil && catalog.HasAsset(resolveAsset(base))) {
+		rank = len(base)
+	}
+	if catalog != nil && catalog.HasExchange(exchange) && catalog.HasMarket(exchange, resolveAsset(base), resolveAsset(quote)) {
+		rank += 1000
+	}
+	return rank
+}
+
+func resolveAsset(asset string) string {
+	if alias, ok := assetAliases[asset]; ok {
+		return alias
+	}
+	return asset
+}
+
+// isPair is true if the line is a market instruction. How the symbol splits doesn't matter here, so there's no catalog.
+func isPair(line string) bool {
+	result := rxPair.FindStringSubmatch(strings.ToUpper(line))
+	if len(result) == 0 {
+		return false
+	}
+	_, _, ok := resolvePair(result[2], nil, "")
+	return ok
+}
@@ -0,0 +1,105 @@
+package signaltranspiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/marianogappa/hts/marketcatalog"
+)
+
+func TestResolvePair(t *testing.T) {
+	ts := []struct {
+		symbol        string
+		expectedBase  string
+		expectedQuote string
+		expectedOk    bool
+	}{
+		{symbol: "BTC/USDT", expectedBase: "BTC", expectedQuote: "USDT", expectedOk: true},
+		{symbol: "BTC_USDT", expectedBase: "BTC", expectedQuote: "USDT", expectedOk: true},
+		{symbol: "BTC-USDT", expectedBase: "BTC", expectedQuote: "USDT", expectedOk: true},
+		{symbol: "BTCUSDT", expectedBase: "BTC", expectedQuote: "USDT", expectedOk: true},
+		{symbol: "BTCBUSD", expectedBase: "BTC", expectedQuote: "BUSD", expectedOk: true},
+		{symbol: "ETHBTC", expectedBase: "ETH", expectedQuote: "BTC", expectedOk: true},
+		{symbol: "#ETHUSDT", expectedBase: "ETH", expectedQuote: "USDT", expectedOk: true},
+		{symbol: "1INCH-USDT", expectedBase: "1INCH", expectedQuote: "USDT", expectedOk: true},
+		{symbol: "1000SHIB/USDT", expectedBase: "1000SHIB", expectedQuote: "USDT", expectedOk: true},
+		{symbol: "SHIB1000USDT", expectedBase: "SHIB1000", expectedQuote: "USDT", expectedOk: true},
+		{symbol: "XBT/USD", expectedBase: "BTC", expectedQuote: "USD", expectedOk: true},
+		{symbol: "XBTUSD", expectedBase: "BTC", expectedQuote: "USD", expectedOk: true},
+		{symbol: "XDG/XBT", expectedBase: "DOGE", expectedQuote: "BTC", expectedOk: true},
+		{symbol: "DOTUSD", expectedBase: "DOT", expectedQuote: "USD", expectedOk: true},
+		{symbol: "BATUSD", expectedBase: "BAT", expectedQuote: "USD", expectedOk: true},
+		{symbol: "GRTUSD", expectedBase: "GRT", expectedQuote: "USD", expectedOk: true},
+		{symbol: "ENTRY"},
+		{symbol: "USDT"},
+		{symbol: "BTC"},
+		{symbol: "B/USDT"},
+		{symbol: "BTC/"},
+		{symbol: "BTC/USDT/ETH"},
+		{symbol: "1000/USDT"},
+	}
+	catalog := marketcatalog.Default()
+	for _, tc := range ts {
+		t.Run(tc.symbol, func(t *testing.T) {
+			base, quote, ok := resolvePair(tc.symbol, catalog, DefaultExchange)
+			if base != tc.expectedBase || quote != tc.expectedQuote || ok != tc.expectedOk {
+				t.Errorf("expected %v %v %v, got %v %v %v", tc.expectedBase, tc.expectedQuote, tc.expectedOk, base, quote, ok)
+			}
+		})
+	}
+}
+
+func TestResolvePairRanking(t *testing.T) {
+	catalog, err := marketcatalog.Load(strings.NewReader(`{"exchanges": {"kraken": ["GRT/EUR", "DO/TUSD"], "binance": ["DOT/USDT"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := []struct {
+		name          string
+		symbol        string
+		catalog       *marketcatalog.Catalog
+		exchange      string
+		expectedBase  string
+		expectedQuote string
+	}{
+		{name: "listed on the exchange", symbol: "DOTUSD", catalog: catalog, exchange: "kraken", expectedBase: "DO", expectedQuote: "TUSD"},
+		{name: "longer known base", symbol: "DOTUSD", catalog: catalog, exchange: "binance", expectedBase: "DOT", expectedQuote: "USD"},
+		{name: "known base", symbol: "GRTUSD", catalog: catalog, exchange: "binance", expectedBase: "GRT", expectedQuote: "USD"},
+		{name: "unknown exchange", symbol: "GRTUSD", catalog: catalog, exchange: "kucoin", expectedBase: "GRT", expectedQuote: "USD"},
+		{name: "alias without a catalog", symbol: "XBTUSD", expectedBase: "BTC", expectedQuote: "USD"},
+		{name: "longer quote without a catalog", symbol: "DOTUSD", expectedBase: "DO", expectedQuote: "TUSD"},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			base, quote, ok := resolvePair(tc.symbol, tc.catalog, tc.exchange)
+			if base != tc.expectedBase || quote != tc.expectedQuote || !ok {
+				t.Errorf("expected %v/%v, got %v/%v (%v)", tc.expectedBase, tc.expectedQuote, base, quote, ok)
+			}
+		})
+	}
+}
+
+func TestIsPair(t *testing.T) {
+	ts := []struct {
+		line     string
+		expected bool
+	}{
+		{line: "BTC/USDT", expected: true},
+		{line: "MARKET: btc/usdt", expected: true},
+		{line: "PAIR: ETHUSDT // spot", expected: true},
+		{line: "  #solusdt", expected: true},
+		{line: "SYMBOL: XBT-USD", expected: true},
+		{line: "ENTRY"},
+		{line: "LONG"},
+		{line: "BINANCE"},
+		{line: "BTC USDT"},
+		{line: "ENTER: 30000 - 31000"},
+	}
+	for _, tc := range ts {
+		t.Run(tc.line, func(t *testing.T) {
+			if actual := isPair(tc.line); actual != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package signaltranspiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+// HasPendingPercentages answers if the signal has percentage take profits or stop loss that couldn't be resolved
+// into prices yet, because it enters immediately and the entry price is unknown until the signal is checked.
+func (o SignalTranspilerOutput) HasPendingPercentages() bool {
+	return o.pendingPercentages
+}
+
+// ResolvePercentages turns percentage take profits and stop loss into prices relative to the given entry price, e.g.
+// the first candle's price for signals that enter immediately.
+func (o *SignalTranspilerOutput) ResolvePercentages(entryPrice common.JsonFloat64) {
+	o.resolvePercentages(entryPrice, entryPrice)
+}
+
+// resolvePercentages resolves percentages against the worst side of the enter range for the trade: take profits of a
+// LONG go up from its high end and its stop loss goes down from its low end, and the other way around for a SHORT.
+// The resolved prices are also shown in the tokenized input.
+func (o *SignalTranspilerOutput) resolvePercentages(low, high common.JsonFloat64) {
+	if !o.pendingPercentages {
+		return
+	}
+	o.pendingPercentages = false
+	takeProfitBase, stopLossBase, direction := high, low, 1.0
+	if o.SignalInput.IsShort {
+		takeProfitBase, stopLossBase, direction = low, high, -1.0
+	}
+	for _, si := range o.instructions {
+		if len(si.percentages) == 0 || si.err != nil {
+			continue
+		}
+		var (
+			prices  = []common.JsonFloat64{}
+			label   = "TAKE PROFIT"
+			base    = float64(takeProfitBase)
+			towards = direction
+		)
+		if si.name == instrNameStopLoss {
+			label, base, towards = "STOP LOSS", float64(stopLossBase), -direction
+		}
+		for _, percentage := range si.percentages {
+			prices = append(prices, roundPrice(base*(1+towards*percentage/100)))
+		}
+		if si.name == instrNameStopLoss {
+			o.SignalInput.StopLoss = prices[0]
+		} else {
+			o.SignalInput.TakeProfits = append(o.SignalInput.TakeProfits, prices...)
+		}
+		// N.B. keep the trailing tokens, e.g. comments
+		trailingTokens := si.tokenizedInput[len(percentageTokens(label, si.percentages, nil)):]
+		si.tokenizedInput = append(percentageTokens(label, si.percentages, prices), trailingTokens...)
+	}
+	if o.TokenizedInput != nil {
+		o.tokenize()
+	}
+}
+
+// roundPrice drops floating point noise (e.g. 121.00000000000001) by keeping 10 significant digits.
+func roundPrice(price float64) common.JsonFloat64 {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(price, 'g', 10, 64), 64)
+	return common.JsonFloat64(rounded)
+}
+
+// percentageTokens tokenizes e.g. "TAKE PROFIT: 5% (31500), 10% (33000)". prices may be nil if not resolved yet.
+func percentageTokens(instruction string, percentages []float64, prices []common.JsonFloat64) []InputToken {
+	tokens := []InputToken{
+		{Input: instruction, TokenType: TOKEN_INSTRUCTION},
+		{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+	}
+	for i, percentage := range percentages {
+		if i > 0 {
+			tokens = append(tokens, InputToken{Input: ", ", TokenType: TOKEN_PUNCTUATION})
+		}
+		tokens = append(tokens, InputToken{Input: fmt.Sprintf("%v%%", percentage), TokenType: TOKEN_EXPRESSION})
+		if prices != nil {
+			price, _ := json.Marshal(prices[i])
+			tokens = append(tokens,
+				InputToken{Input: " (", TokenType: TOKEN_PUNCTUATION},
+				InputToken{Input: string(price), TokenType: TOKEN_EXPRESSION},
+				InputToken{Input: ")", TokenType: TOKEN_PUNCTUATION},
+			)
+		}
+	}
+	return tokens
+}
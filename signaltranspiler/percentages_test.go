@@ -0,0 +1,88 @@
+package signaltranspiler
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+func TestResolvePercentages(t *testing.T) {
+	ts := []struct {
+		name                string
+		input               string
+		expectedTakeProfits []common.JsonFloat64
+		expectedStopLoss    common.JsonFloat64
+	}{
+		{
+			name:                "long goes up from the high end and stops below the low end",
+			input:               "LONG\nTP: 10%, 20%\nSL: 5%",
+			expectedTakeProfits: []common.JsonFloat64{121, 132},
+			expectedStopLoss:    95,
+		},
+		{
+			name:                "short goes down from the low end and stops above the high end",
+			input:               "SHORT\nTP: 10%, 20%\nSL: 5%",
+			expectedTakeProfits: []common.JsonFloat64{90, 80},
+			expectedStopLoss:    115.5,
+		},
+		{
+			name:                "signs are ignored, the direction decides",
+			input:               "SHORT\nTP: +10%\nSL: -5%",
+			expectedTakeProfits: []common.JsonFloat64{90},
+			expectedStopLoss:    115.5,
+		},
+		{
+			name:                "mixed with prices",
+			input:               "LONG\nTP: 10%\nSL: 90",
+			expectedTakeProfits: []common.JsonFloat64{121},
+			expectedStopLoss:    90,
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := NewSignalTranspiler().Transpile("BTC/USDT\nENTER: 100 - 110\nSTART AT: 2021-06-22T15:21:00Z\n" + tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.HasPendingPercentages() {
+				t.Fatalf("expected percentages to be resolved against the enter range")
+			}
+			if !reflect.DeepEqual(output.SignalInput.TakeProfits, tc.expectedTakeProfits) || output.SignalInput.StopLoss != tc.expectedStopLoss {
+				t.Errorf("expected take profits %v and stop loss %v, got %v and %v",
+					tc.expectedTakeProfits, tc.expectedStopLoss, output.SignalInput.TakeProfits, output.SignalInput.StopLoss)
+			}
+		})
+	}
+}
+
+func TestResolvePercentagesEnteringImmediately(t *testing.T) {
+	ts := []struct {
+		name                string
+		direction           string
+		expectedTakeProfits []common.JsonFloat64
+		expectedStopLoss    common.JsonFloat64
+	}{
+		{name: "long", direction: "LONG", expectedTakeProfits: []common.JsonFloat64{220}, expectedStopLoss: 190},
+		{name: "short", direction: "SHORT", expectedTakeProfits: []common.JsonFloat64{180}, expectedStopLoss: 210},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := NewSignalTranspiler().Transpile("BTC/USDT\nENTER: NOW\nSTART AT: 2021-06-22T15:21:00Z\nTP: 10%\nSL: 5%\n" + tc.direction)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !output.HasPendingPercentages() || len(output.SignalInput.TakeProfits) != 0 {
+				t.Fatalf("expected percentages to wait for the entry price, got %v", output.SignalInput.TakeProfits)
+			}
+			output.ResolvePercentages(200)
+			if output.HasPendingPercentages() {
+				t.Fatalf("expected percentages to be resolved")
+			}
+			if !reflect.DeepEqual(output.SignalInput.TakeProfits, tc.expectedTakeProfits) || output.SignalInput.StopLoss != tc.expectedStopLoss {
+				t.Errorf("expected take profits %v and stop loss %v, got %v and %v",
+					tc.expectedTakeProfits, tc.expectedStopLoss, output.SignalInput.TakeProfits, output.SignalInput.StopLoss)
+			}
+		})
+	}
+}
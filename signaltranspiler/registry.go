@@ -0,0 +1,124 @@
+package signaltranspiler
+
+import "sort"
+
+// InstructionResult is the outcome of an instruction matching a line. Tokens are the line's normalized tokens, Err
+// makes the line an error (it should have at least one TOKEN_ERROR token), and Warning is reported without failing.
+type InstructionResult struct {
+	Tokens  []InputToken
+	Err     error
+	Warning error
+
+	percentages []float64
+}
+
+// Tokenizer tries to read a line as an instruction. If the line is not the tokenizer's instruction, ok is false and
+// sto must be left untouched. Otherwise, the tokenizer records the instruction's effect on sto, e.g. on
+// sto.SignalInput or sto.Custom, and returns the line's tokens.
+type Tokenizer func(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (result InstructionResult, ok bool)
+
+// InstructionMatcher is one kind of instruction. Every line goes to the matchers in ascending Priority order until
+// one matches. Directive matchers are tried on every line before any other matcher, so that they can change how the
+// rest of the signal is read, e.g. TIMEZONE.
+type InstructionMatcher struct {
+	Name      string
+	Priority  int
+	Directive bool
+	Tokenize  Tokenizer
+}
+
+// InstructionRegistry is the set of instructions a SignalTranspiler understands. It is not safe to change it while
+// transpiling.
+type InstructionRegistry struct {
+	matchers []InstructionMatcher
+}
+
+// NewInstructionRegistry returns a registry with the built-in instructions. Their priorities are multiples of 10, so
+// that custom instructions can go in between.
+func NewInstructionRegistry() *InstructionRegistry {
+	r := &InstructionRegistry{}
+	for i, instruction := range instructions {
+		r.Register(InstructionMatcher{
+			Name:      instruction.name(),
+			Priority:  (i + 1) * 10,
+			Directive: directiveNames[instruction.name()],
+			Tokenize:  instruction.apply,
+		})
+	}
+	return r
+}
+
+// Register adds a matcher. Matchers with the same priority are tried in the order they were registered.
+func (r *InstructionRegistry) Register(m InstructionMatcher) {
+	r.matchers = append(r.matchers, m)
+	r.sort()
+}
+
+// Remove removes every matcher with the given name, e.g. both ways of writing "TAKE PROFIT".
+func (r *InstructionRegistry) Remove(name string) {
+	matchers := []InstructionMatcher{}
+	for _, m := range r.matchers {
+		if m.Name != name {
+			matchers = append(matchers, m)
+		}
+	}
+	r.matchers = matchers
+}
+
+// SetPriority moves every matcher with the given name to a new priority. It returns false if there is none.
+func (r *InstructionRegistry) SetPriority(name string, priority int) bool {
+	found := false
+	for i := range r.matchers {
+		if r.matchers[i].Name == name {
+			r.matchers[i].Priority = priority
+			found = true
+		}
+	}
+	r.sort()
+	return found
+}
+
+// Matchers returns a copy of the matchers, in the order they are tried.
+func (r *InstructionRegistry) Matchers() []InstructionMatcher {
+	return append([]InstructionMatcher{}, r.matchers...)
+}
+
+func (r *InstructionRegistry) sort() {
+	sort.SliceStable(r.matchers, func(i, j int) bool { return r.matchers[i].Priority < r.matchers[j].Priority })
+}
+
+// WithInstruction registers a custom instruction.
+func WithInstruction(m InstructionMatcher) Option {
+	return func(t *SignalTranspiler) {
+		t.Registry().Register(m)
+	}
+}
+
+// Registry returns the transpiler's instructions, which can be changed to support custom syntax.
+func (t *SignalTranspiler) Registry() *InstructionRegistry {
+	if t.registry == nil {
+		t.registry = NewInstructionRegistry()
+	}
+	return t.registry
+}
+
+// matchers returns the matchers to try, including only directives if directives is set.
+func (t SignalTranspiler) matchers(directives bool) []InstructionMatcher {
+	registry := t.registry
+	if registry == nil {
+		registry = defaultRegistry
+	}
+	if !directives {
+		return registry.matchers
+	}
+	matchers := []InstructionMatcher{}
+	for _, m := range registry.matchers {
+		if m.Directive {
+			matchers = append(matchers, m)
+		}
+	}
+	return matchers
+}
+
+// defaultRegistry is used by the zero SignalTranspiler. It's never changed.
+var defaultRegistry = NewInstructionRegistry()
@@ -0,0 +1,134 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const registryTestSignal = "BTC/USDT\n\n// comment\nENTER: 30000 - 31000\nSTART AT: 2021-06-22T15:21:00Z\nSL: 29000"
+
+var rxNote = regexp.MustCompile(`^\s*NOTE:\s*(.+)$`)
+
+func noteMatcher(priority int) InstructionMatcher {
+	return InstructionMatcher{
+		Name:     "NOTE",
+		Priority: priority,
+		Tokenize: func(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool) {
+			result := rxNote.FindStringSubmatch(rawInput)
+			if len(result) == 0 {
+				return InstructionResult{}, false
+			}
+			sto.Custom["note"] = result[1]
+			return InstructionResult{Tokens: []InputToken{
+				{Input: "NOTE", TokenType: TOKEN_INSTRUCTION},
+				{Input: ": ", TokenType: TOKEN_PUNCTUATION},
+				{Input: result[1], TokenType: TOKEN_EXPRESSION},
+			}}, true
+		},
+	}
+}
+
+func matcherNames(r *InstructionRegistry) []string {
+	names := []string{}
+	for _, m := range r.Matchers() {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+func TestRegistryRegister(t *testing.T) {
+	transpiler := NewSignalTranspiler(WithInstruction(noteMatcher(5)))
+	output, err := transpiler.Transpile(registryTestSignal + "\nNOTE: from channel A")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Custom["note"] != "from channel A" {
+		t.Errorf("expected the custom instruction to record its note, got %v", output.Custom)
+	}
+	if names := matcherNames(transpiler.Registry()); names[0] != "NOTE" {
+		t.Errorf("expected NOTE to be tried first, got %v", names)
+	}
+
+	// Matchers with the same priority are tried in the order they were registered
+	r := NewInstructionRegistry()
+	r.Register(InstructionMatcher{Name: "FIRST", Priority: 15})
+	r.Register(InstructionMatcher{Name: "SECOND", Priority: 15})
+	names := strings.Join(matcherNames(r), ",")
+	if !strings.Contains(names, ",FIRST,SECOND,") {
+		t.Errorf("expected FIRST then SECOND, got %v", names)
+	}
+
+	// Registering doesn't change the transpilers that don't use it
+	if _, err := NewSignalTranspiler().Transpile(registryTestSignal + "\nNOTE: from channel A"); !errors.Is(err, ErrUnrecognizedInstruction) {
+		t.Errorf("expected error %v, got %v", ErrUnrecognizedInstruction, err)
+	}
+}
+
+func TestRegistryRemove(t *testing.T) {
+	ts := []struct {
+		name        string
+		expectedErr error
+	}{
+		{name: instrNameEmpty, expectedErr: ErrUnrecognizedInstruction},
+		{name: instrNameStopLoss, expectedErr: ErrUnrecognizedInstruction},
+		{name: instrNameStartAt, expectedErr: ErrUnrecognizedInstruction},
+		{name: instrNameTimezone},
+		{name: "UNKNOWN"},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			transpiler := NewSignalTranspiler()
+			transpiler.Registry().Remove(tc.name)
+			for _, name := range matcherNames(transpiler.Registry()) {
+				if name == tc.name {
+					t.Fatalf("expected %v to be removed", tc.name)
+				}
+			}
+			if _, err := transpiler.Transpile(registryTestSignal); !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestRegistrySetPriority(t *testing.T) {
+	ts := []struct {
+		name     string
+		priority int
+	}{
+		{name: instrNameStartAt, priority: 1},
+		{name: instrNameStopLoss, priority: 1},
+		{name: instrNameMarket, priority: 1000},
+		{name: instrNameEmpty, priority: 1000},
+	}
+	expected, err := NewSignalTranspiler().Transpile(registryTestSignal)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			transpiler := NewSignalTranspiler()
+			if !transpiler.Registry().SetPriority(tc.name, tc.priority) {
+				t.Fatalf("expected %v to be found", tc.name)
+			}
+			names := matcherNames(transpiler.Registry())
+			if (tc.priority == 1 && names[0] != tc.name) || (tc.priority == 1000 && names[len(names)-1] != tc.name) {
+				t.Errorf("expected %v to move, got %v", tc.name, names)
+			}
+			// Every line is still read as the same instruction
+			output, err := transpiler.Transpile(registryTestSignal)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(output.SignalInput, expected.SignalInput) {
+				t.Errorf("expected %+v, got %+v", expected.SignalInput, output.SignalInput)
+			}
+		})
+	}
+	if NewSignalTranspiler().Registry().SetPriority("UNKNOWN", 1) {
+		t.Errorf("expected an unknown instruction not to be found")
+	}
+}
@@ -0,0 +1,94 @@
+package signaltranspiler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+// Render is the reverse of Transpile: it produces signal text that transpiles back to the given input. Fields that
+// can't be expressed as instructions, or that NewSignalTranspiler() wouldn't accept, like an exchange it doesn't
+// support, are rendered as comments, so they are visible but lost on the way back.
+func Render(input common.SignalCheckInput) string {
+	lines := []string{}
+	if input.BaseAsset != "" || input.QuoteAsset != "" {
+		lines = append(lines, fmt.Sprintf("MARKET: %v/%v", strings.ToUpper(input.BaseAsset), strings.ToUpper(input.QuoteAsset)))
+	}
+	switch {
+	case isSInSS(strings.ToUpper(input.Exchange), supportedExchangeList):
+		lines = append(lines, fmt.Sprintf("EXCHANGE: %v", strings.ToUpper(input.Exchange)))
+	case input.Exchange != "":
+		lines = append(lines, fmt.Sprintf("// unsupported: exchange %v", input.Exchange))
+	}
+	if input.IsShort {
+		lines = append(lines, "SHORT")
+	} else {
+		lines = append(lines, "LONG")
+	}
+	if input.InitialISO8601 != "" {
+		lines = append(lines, fmt.Sprintf("START AT: %v", input.InitialISO8601))
+	}
+	switch {
+	case input.EnterRangeLow == -1 && input.EnterRangeHigh == -1:
+		lines = append(lines, "ENTER: IMMEDIATELY")
+	case input.EnterRangeLow != 0 || input.EnterRangeHigh != 0:
+		lines = append(lines, fmt.Sprintf("ENTER BETWEEN: %v - %v", renderFloat(input.EnterRangeLow), renderFloat(input.EnterRangeHigh)))
+	}
+	allocations, isAllocated := takeProfitAllocations(input)
+	switch {
+	case len(input.TakeProfits) > 0 && isAllocated:
+		allocated := []string{}
+		for i, tp := range input.TakeProfits {
+			allocated = append(allocated, fmt.Sprintf("%v (%v%%)", renderFloat(tp), renderPercentage(float64(allocations[i]))))
+		}
+		lines = append(lines, fmt.Sprintf("TAKE PROFIT: %v", strings.Join(allocated, ", ")))
+	case len(input.TakeProfits) > 0:
+		lines = append(lines, fmt.Sprintf("TAKE PROFIT: %v", renderFloats(input.TakeProfits)))
+	}
+	switch {
+	case input.StopLoss == -1:
+		lines = append(lines, "STOP LOSS: NONE")
+	case input.StopLoss != 0:
+		lines = append(lines, fmt.Sprintf("STOP LOSS: %v", renderFloat(input.StopLoss)))
+	}
+	timeout := time.Duration(input.InvalidateAfterSeconds) * time.Second
+	switch {
+	case input.InvalidateAfterSeconds > 0 && input.InvalidateAfterSeconds%60 == 0 && timeout <= DefaultMaxTimeout:
+		lines = append(lines, fmt.Sprintf("TIMEOUT AFTER: %v", formatTimeout(timeout)))
+	case input.InvalidateAfterSeconds > 0:
+		lines = append(lines, fmt.Sprintf("// unsupported: timeout after %v seconds", input.InvalidateAfterSeconds))
+	}
+	if input.InvalidateISO8601 != "" {
+		lines = append(lines, fmt.Sprintf("// unsupported: invalidate at %v", input.InvalidateISO8601))
+	}
+	if len(input.TakeProfitRatios) > 0 && !isAllocated {
+		lines = append(lines, fmt.Sprintf("// unsupported: take profit ratios %v", renderFloats(input.TakeProfitRatios)))
+	}
+	for i, stopAt := range []bool{input.IfTP1StopAtEntry, input.IfTP2StopAtTP1, input.IfTP3StopAtTP2, input.IfTP4StopAtTP3} {
+		if stopAt {
+			lines = append(lines, fmt.Sprintf("// unsupported: move stop loss after take profit %v", i+1))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderPercentage renders a ratio like 0.3 as "30", without floating point noise like "30.000000000000004".
+func renderPercentage(ratio float64) string {
+	return strconv.FormatFloat(math.Round(ratio*1e6)/1e4, 'f', -1, 64)
+}
+
+func renderFloat(f common.JsonFloat64) string {
+	return strconv.FormatFloat(float64(f), 'f', -1, 64)
+}
+
+func renderFloats(fs []common.JsonFloat64) string {
+	ss := []string{}
+	for _, f := range fs {
+		ss = append(ss, renderFloat(f))
+	}
+	return strings.Join(ss, ", ")
+}
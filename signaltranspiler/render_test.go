@@ -0,0 +1,150 @@
+package signaltranspiler
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+// TestRenderRoundTrip checks that random inputs, made only of fields that signal text can express, transpile back
+// from their rendered text unchanged.
+func TestRenderRoundTrip(t *testing.T) {
+	var (
+		r         = rand.New(rand.NewSource(1))
+		exchanges = []string{common.BINANCE, common.BINANCE_USDM_FUTURES, common.COINBASE, common.KRAKEN, common.KUCOIN, common.FTX}
+		markets   = [][]string{{"BTC", "USDT"}, {"ETH", "USDT"}}
+		price     = func(min, max float64) float64 { return math.Round((min+r.Float64()*(max-min))*100) / 100 }
+	)
+	for i := 0; i < 1000; i++ {
+		market := markets[r.Intn(len(markets))]
+		in := common.SignalCheckInput{
+			ReturnCandlesticks: true,
+			BaseAsset:          market[0],
+			QuoteAsset:         market[1],
+			Exchange:           exchanges[r.Intn(len(exchanges))],
+			IsShort:            r.Intn(2) == 0,
+			InitialISO8601:     common.ISO8601(time.Date(2021, time.Month(1+r.Intn(12)), 1+r.Intn(28), r.Intn(24), r.Intn(60), 0, 0, time.UTC).Format(time.RFC3339)),
+			// Whole minutes up to the longest timeout
+			InvalidateAfterSeconds: 60 * (1 + r.Intn(int(DefaultMaxTimeout/time.Minute))),
+		}
+		low := price(500, 600)
+		high := price(low, low+10)
+		if r.Intn(3) == 0 {
+			in.EnterRangeLow, in.EnterRangeHigh = -1, -1
+		} else {
+			in.EnterRangeLow, in.EnterRangeHigh = common.JsonFloat64(low), common.JsonFloat64(high)
+		}
+		takeProfits := r.Intn(5)
+		for j := 1; j <= takeProfits; j++ {
+			if in.IsShort {
+				in.TakeProfits = append(in.TakeProfits, common.JsonFloat64(low-float64(j)*20))
+			} else {
+				in.TakeProfits = append(in.TakeProfits, common.JsonFloat64(high+float64(j)*20))
+			}
+		}
+		if len(in.TakeProfits) > 0 && r.Intn(2) == 0 {
+			// Whole percentages that add up to 100
+			allocations, left := []common.JsonFloat64{}, 100
+			for j := range in.TakeProfits {
+				percentage := left
+				if j < len(in.TakeProfits)-1 {
+					percentage = 1 + r.Intn(left-(len(in.TakeProfits)-1-j))
+				}
+				allocations = append(allocations, common.JsonFloat64(float64(percentage)/100))
+				left -= percentage
+			}
+			in.TakeProfitRatios = takeProfitRatios(allocations)
+		}
+		switch r.Intn(3) {
+		case 0:
+			in.StopLoss = -1
+		case 1:
+			if in.IsShort {
+				in.StopLoss = common.JsonFloat64(price(high+1, high+50))
+			} else {
+				in.StopLoss = common.JsonFloat64(price(low-50, low-1))
+			}
+		}
+
+		rendered := Render(in)
+		output, err := NewSignalTranspiler().Transpile(rendered)
+		if err != nil {
+			t.Fatalf("rendered text doesn't transpile: %v\n%v", err, rendered)
+		}
+		if !reflect.DeepEqual(output.SignalInput, in) {
+			t.Fatalf("rendered text transpiles to a different input\n%v\nexpected %+v\ngot      %+v", rendered, in, output.SignalInput)
+		}
+	}
+}
+
+// TestRenderUnsupported checks that inputs with fields that signal text can't express, or that the transpiler
+// doesn't accept by default, still render to text that transpiles.
+func TestRenderUnsupported(t *testing.T) {
+	base := func() common.SignalCheckInput {
+		return common.SignalCheckInput{
+			BaseAsset:              "BTC",
+			QuoteAsset:             "USDT",
+			Exchange:               common.BINANCE,
+			InitialISO8601:         "2021-06-22T15:21:00Z",
+			EnterRangeLow:          30000,
+			EnterRangeHigh:         31000,
+			TakeProfits:            []common.JsonFloat64{32000, 33000},
+			StopLoss:               29000,
+			InvalidateAfterSeconds: 86400,
+		}
+	}
+	ts := []struct {
+		name    string
+		change  func(in *common.SignalCheckInput)
+		comment string
+	}{
+		{
+			name:    "exchange that the transpiler doesn't support",
+			change:  func(in *common.SignalCheckInput) { in.Exchange = common.HUOBI },
+			comment: "// unsupported: exchange huobi",
+		},
+		{
+			name:    "timeout over the maximum",
+			change:  func(in *common.SignalCheckInput) { in.InvalidateAfterSeconds = 31 * 86400 },
+			comment: "// unsupported: timeout after 2678400 seconds",
+		},
+		{
+			name:    "timeout in seconds",
+			change:  func(in *common.SignalCheckInput) { in.InvalidateAfterSeconds = 90 },
+			comment: "// unsupported: timeout after 90 seconds",
+		},
+		{
+			name:    "invalidate at a date",
+			change:  func(in *common.SignalCheckInput) { in.InvalidateISO8601 = "2021-06-23T15:21:00Z" },
+			comment: "// unsupported: invalidate at 2021-06-23T15:21:00Z",
+		},
+		{
+			name:    "ratios for some take profits only",
+			change:  func(in *common.SignalCheckInput) { in.TakeProfitRatios = []common.JsonFloat64{1} },
+			comment: "// unsupported: take profit ratios 1",
+		},
+		{
+			name:    "stop loss moved after a take profit",
+			change:  func(in *common.SignalCheckInput) { in.IfTP1StopAtEntry = true },
+			comment: "// unsupported: move stop loss after take profit 1",
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			in := base()
+			tc.change(&in)
+			rendered := Render(in)
+			if !isSInSS(tc.comment, strings.Split(rendered, "\n")) {
+				t.Errorf("expected the line %q in\n%v", tc.comment, rendered)
+			}
+			if _, err := NewSignalTranspiler().Transpile(rendered); err != nil {
+				t.Errorf("rendered text doesn't transpile: %v\n%v", err, rendered)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package signaltranspiler
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// rxInstructionStart finds where an instruction may start in a line that holds several of them, e.g.
+// "BTC/USDT LONG" or "SL: 29000 TP: 32000, 33000": at a keyword, a bare exchange, a pair or a hashtag like #BTCUSDT.
+var rxInstructionStart = regexp.MustCompile(`(?:^|[\s,;|])((?:` + instructionKeywords() + `)\b|[[:digit:]]*[[:upper:]]{2,}[[:digit:]]*[/_-][[:upper:]]{2,}\b|#[[:digit:]]*[[:upper:]]{2,})`)
+
+func instructionKeywords() string {
+	keywords := []string{
+		"PAIR", "SYMBOL", "MARKET", "ENTER", "TAKE PROFIT", `TP\d*`, "STOP LOSS", "SL", "EXCHANGE", "PLATFORM",
+		"TIMEOUT", "INVALIDATE", "EXPIRES?", "START AT", "START", "FROM", "TIMEZONE", "TIME ZONE", "TZ", "LONG",
+		"SHORT", "STRICT", "TRAILING STOP", "TRAILING SL", "TRAIL", "BREAK ?EVEN",
+		"LEVERAGE", "LEV", "MARGIN", "CROSS", "ISOLATED",
+	}
+	for _, exchange := range exchangeList {
+		keywords = append(keywords, regexp.QuoteMeta(exchange))
+	}
+	return strings.Join(keywords, "|")
+}
+
+// applySegments applies a line that no instruction recognizes as a sequence of instructions. From the start of the
+// line, it takes the longest piece up to an instruction keyword that some instruction recognizes, and so on until the
+// end of the line. If any piece isn't recognized, or has an error when withoutErrors is set, the line isn't changed and
+// the input is returned as is.
+func (si *signalInstruction) applySegments(t SignalTranspiler, input SignalTranspilerOutput, withoutErrors bool) (SignalTranspilerOutput, bool) {
+	code := si.rawInput
+	comment := trailingComment(si.rawInput)
+	if comment != "" {
+		code = code[:strings.Index(code, "//")]
+	}
+	starts := segmentStarts(code)
+	if len(starts) == 0 {
+		return input, false
+	}
+	ends := append(starts, len(code))
+
+	var (
+		output   = input
+		segments = []*signalInstruction{}
+		errs     = []error{}
+		start    = 0
+	)
+	for start < len(code) {
+		var (
+			segment *signalInstruction
+			next    SignalTranspilerOutput
+			end     int
+			err     error
+		)
+		// The longest piece wins, so that e.g. "TIMEZONE: Europe/Paris" isn't split at what looks like a pair
+		for i := len(ends) - 1; i >= 0 && segment == nil; i-- {
+			if ends[i] <= start || (start == 0 && ends[i] == len(code)) {
+				continue
+			}
+			candidate := si.newSegment(code, start, ends[i], comment)
+			var matched bool
+			next, matched, err = candidate.applyFirstMatch(t, output, t.matchers(false))
+			if matched {
+				segment, end = candidate, ends[i]
+			}
+		}
+		if segment == nil || (withoutErrors && err != nil) {
+			return input, false
+		}
+		output = next
+		segments = append(segments, segment)
+		errs = append(errs, err)
+		start = end
+	}
+	for i, segment := range segments {
+		if errs[i] != nil {
+			output.addError(errs[i], segment)
+		}
+	}
+	si.segments = segments
+	si.isApplied = true
+	return output, true
+}
+
+// segmentStarts returns where instructions after the first one may start in code, a line without its comment.
+func segmentStarts(code string) []int {
+	starts := []int{}
+	for _, match := range rxInstructionStart.FindAllStringSubmatchIndex(asciiUpper(code), -1) {
+		if strings.TrimSpace(code[:match[2]]) != "" {
+			starts = append(starts, match[2])
+		}
+	}
+	return starts
+}
+
+// newSegment makes an instruction out of code[start:end]. The line's comment goes with the last segment.
+func (si *signalInstruction) newSegment(code string, start, end int, comment string) *signalInstruction {
+	text := strings.TrimRight(code[start:end], " \t,;|")
+	segment := newSignalInstruction(strings.TrimSpace(text), si.lineNumber, si.isInferred)
+	if end == len(code) && comment != "" {
+		segment.rawInput += " " + comment
+	}
+	leading := len(text) - len(strings.TrimLeft(text, " \t"))
+	segment.separator = code[:start+leading][len(strings.TrimRight(code[:start], " \t,;|")):]
+	if si.sourceLine != "" {
+		segment.sourceLine = si.sourceLine
+		return segment
+	}
+	segment.span = []int{
+		utf8.RuneCountInString(code[:start+leading]),
+		utf8.RuneCountInString(code[:start+len(text)]),
+	}
+	return segment
+}
+
+// flattenSegments replaces the lines that hold several instructions with those instructions.
+func flattenSegments(signalInstructions []*signalInstruction) []*signalInstruction {
+	flattened := []*signalInstruction{}
+	for _, si := range signalInstructions {
+		if len(si.segments) > 0 {
+			flattened = append(flattened, si.segments...)
+			continue
+		}
+		flattened = append(flattened, si)
+	}
+	return flattened
+}
@@ -0,0 +1,124 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// segment is what a piece of a line that holds several instructions was read as.
+type segment struct {
+	rawInput, separator, tokens string
+	span                        []int
+	isError                     bool
+}
+
+func TestSegments(t *testing.T) {
+	const rest = "\nENTER: 30000 - 31000\nSTART AT: 2021-06-22T15:21:00Z"
+	ts := []struct {
+		name        string
+		input       string
+		expected    []segment
+		expectedErr error
+	}{
+		{
+			name:  "market and direction",
+			input: "BTC/USDT LONG" + rest,
+			expected: []segment{
+				{rawInput: "BTC/USDT", tokens: "MARKET: BTC/USDT", span: []int{0, 8}},
+				{rawInput: "LONG", separator: " ", tokens: "LONG", span: []int{9, 13}},
+			},
+		},
+		{
+			name:  "whole signal on one line",
+			input: "BTC/USDT SHORT ENTER: 31000 - 32000 TP: 30000, 29000 SL: 33000\nSTART AT: 2021-06-22T15:21:00Z",
+			expected: []segment{
+				{rawInput: "BTC/USDT", tokens: "MARKET: BTC/USDT", span: []int{0, 8}},
+				{rawInput: "SHORT", separator: " ", tokens: "SHORT", span: []int{9, 14}},
+				{rawInput: "ENTER: 31000 - 32000", separator: " ", tokens: "ENTER BETWEEN: 31000 - 32000", span: []int{15, 35}},
+				{rawInput: "TP: 30000, 29000", separator: " ", tokens: "TAKE PROFIT: 30000, 29000", span: []int{36, 52}},
+				{rawInput: "SL: 33000", separator: " ", tokens: "STOP LOSS: 33000", span: []int{53, 62}},
+			},
+		},
+		{
+			name:  "keyword instructions separated by a comma",
+			input: "SL: 29000, TP: 32000, 33000\nBTC/USDT" + rest,
+			expected: []segment{
+				{rawInput: "SL: 29000", tokens: "STOP LOSS: 29000", span: []int{0, 9}},
+				{rawInput: "TP: 32000, 33000", separator: ", ", tokens: "TAKE PROFIT: 32000, 33000", span: []int{11, 27}},
+			},
+		},
+		{
+			name:  "hashtag, pipes and semicolons",
+			input: "#BTCUSDT | LONG ; SL 29000" + rest,
+			expected: []segment{
+				{rawInput: "#BTCUSDT", tokens: "MARKET: BTC/USDT", span: []int{0, 8}},
+				{rawInput: "LONG", separator: " | ", tokens: "LONG", span: []int{11, 15}},
+				{rawInput: "SL 29000", separator: " ; ", tokens: "STOP LOSS: 29000", span: []int{18, 26}},
+			},
+		},
+		{
+			name:  "the comment goes with the last segment",
+			input: "BTC/USDT KRAKEN // note" + rest,
+			expected: []segment{
+				{rawInput: "BTC/USDT", tokens: "MARKET: BTC/USDT", span: []int{0, 8}},
+				{rawInput: "KRAKEN // note", separator: " ", tokens: "EXCHANGE: KRAKEN // note", span: []int{9, 15}},
+			},
+		},
+		{
+			name:        "an error in a segment",
+			input:       "BTC/USDT LONG SHORT" + rest,
+			expectedErr: ErrIsShortAlreadySupplied,
+			expected: []segment{
+				{rawInput: "BTC/USDT", tokens: "MARKET: BTC/USDT", span: []int{0, 8}},
+				{rawInput: "LONG", separator: " ", tokens: "LONG", span: []int{9, 13}},
+				{rawInput: "SHORT", separator: " ", tokens: "SHORT", span: []int{14, 19}, isError: true},
+			},
+		},
+		{
+			name:        "a segment that isn't an instruction leaves the line whole",
+			input:       "BTC/USDT LONG FOO" + rest,
+			expectedErr: ErrUnrecognizedInstruction,
+			expected:    []segment{{rawInput: "BTC/USDT LONG FOO", tokens: "BTC/USDT LONG FOO", isError: true}},
+		},
+		{
+			name:     "what looks like a pair inside an instruction doesn't split it",
+			input:    "TIMEZONE: Europe/Paris\nBTC/USDT" + rest,
+			expected: []segment{{rawInput: "TIMEZONE: Europe/Paris", tokens: "TIMEZONE: Europe/Paris"}},
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := NewSignalTranspiler().Transpile(tc.input)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			actual := []segment{}
+			for _, si := range output.instructions {
+				if si.lineNumber != 0 || si.isInferred {
+					continue
+				}
+				s := segment{rawInput: si.rawInput, separator: si.separator, tokens: joinTokens(si.tokenizedInput), span: si.span}
+				for _, token := range si.tokenizedInput {
+					s.isError = s.isError || token.TokenType == TOKEN_ERROR
+				}
+				if len(si.tokenizedInput) == 0 {
+					s.tokens, s.isError = si.rawInput, true
+				}
+				actual = append(actual, s)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %+v\ngot      %+v", tc.expected, actual)
+			}
+
+			// The line's tokens are its segments' tokens, joined by their separators
+			expectedLine := ""
+			for _, s := range tc.expected {
+				expectedLine += s.separator + s.tokens
+			}
+			if line := joinTokens(output.TokenizedInput[0]); line != expectedLine {
+				t.Errorf("expected the line to read %q, got %q", expectedLine, line)
+			}
+		})
+	}
+}
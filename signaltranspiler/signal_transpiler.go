@@ -4,43 +4,159 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/marianogappa/hts/marketcatalog"
 	"github.com/marianogappa/signal-checker/common"
 )
 
 type SignalTranspiler struct {
+	disabledWarningRules map[string]bool
+	now                  func() time.Time
+	dateParsers          []DateParser
+	maxTimeout           time.Duration
+	defaultExchange      string
+	defaultTimeout       time.Duration
+	supportedExchanges   []string
+	strict               bool
+	registry             *InstructionRegistry
+	dialect              *Dialect
+	extraction           bool
+	marketCatalog        *marketcatalog.Catalog
 }
 
-func NewSignalTranspiler() *SignalTranspiler {
-	return &SignalTranspiler{}
+func NewSignalTranspiler(opts ...Option) *SignalTranspiler {
+	t := &SignalTranspiler{
+		disabledWarningRules: map[string]bool{},
+		now:                  time.Now,
+		dateParsers:          defaultDateParsers(),
+		maxTimeout:           DefaultMaxTimeout,
+		defaultExchange:      DefaultExchange,
+		defaultTimeout:       DefaultTimeout,
+		supportedExchanges:   supportedExchangeList,
+		registry:             NewInstructionRegistry(),
+		marketCatalog:        marketcatalog.Default(),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t SignalTranspiler) currentTime() time.Time {
+	if t.now == nil {
+		return time.Now()
+	}
+	return t.now()
 }
 
 type SignalTranspilerOutput struct {
 	Errors         []string                 `json:"errors"`
 	Warnings       []string                 `json:"warnings"`
-	TokenizedInput [][]inputToken           `json:"tokenizedInput"`
+	Diagnostics    []Diagnostic             `json:"diagnostics"`
+	TokenizedInput [][]InputToken           `json:"tokenizedInput"`
 	SignalInput    common.SignalCheckInput  `json:"signalInput"`
 	SignalOutput   common.SignalCheckOutput `json:"signalOutput"`
-	isShortSet     bool
+
+	// RealizedProfitRatio is SignalOutput's profit ratio, unless signalrunner closed the position before the signal
+	// checker did, e.g. with a trailing stop. It's set along with SignalOutput when the signal is checked.
+	RealizedProfitRatio common.JsonFloat64 `json:"realizedProfitRatio"`
+
+	// Cancelled is set when the signal's check was abandoned because the batch it was in was cancelled. SignalOutput
+	// then has the cancellation as its error.
+	Cancelled bool `json:"cancelled,omitempty"`
+
+	// TakeProfitPercentages and StopLossPercentage are take profits and stop loss given as percentages away from the
+	// entry. They are resolved into SignalInput once the entry price is known (see ResolvePercentages).
+	TakeProfitPercentages []common.JsonFloat64 `json:"takeProfitPercentages,omitempty"`
+	StopLossPercentage    common.JsonFloat64   `json:"stopLossPercentage,omitempty"`
+
+	// TakeProfitAllocations are the portions of the position that each take profit sells, e.g. 0.3 for "32000 (30%)".
+	// Once they are validated, they are sent to the signal checker as SignalInput's TakeProfitRatios.
+	TakeProfitAllocations []common.JsonFloat64 `json:"takeProfitAllocations,omitempty"`
+
+	// TrailingStopPercentage and BreakEvenAfterTakeProfit are exit rules that the signal checker doesn't support.
+	// signalrunner applies them to the candlesticks of the check.
+	TrailingStopPercentage   common.JsonFloat64 `json:"trailingStopPercentage,omitempty"`
+	BreakEvenAfterTakeProfit int                `json:"breakEvenAfterTakeProfit,omitempty"`
+
+	// Leverage and MarginMode ("cross" or "isolated") are only valid on futures exchanges. signalrunner uses them to
+	// work out the leveraged profit ratio and the liquidation price once the signal is checked.
+	Leverage             common.JsonFloat64 `json:"leverage,omitempty"`
+	MarginMode           string             `json:"marginMode,omitempty"`
+	LeveragedProfitRatio common.JsonFloat64 `json:"leveragedProfitRatio,omitempty"`
+	LiquidationPrice     common.JsonFloat64 `json:"liquidationPrice,omitempty"`
+
+	// Custom holds whatever custom instructions (see InstructionRegistry) record about the signal.
+	Custom map[string]interface{} `json:"custom,omitempty"`
+
+	isShortSet         bool
+	isStrict           bool
+	location           *time.Location
+	invalidateAt       time.Time
+	pendingPercentages bool
+	instructions       []*signalInstruction
+	errs               []error
 }
 
 func (o SignalTranspilerOutput) error() error {
-	if len(o.Errors) == 0 {
+	if len(o.errs) == 0 {
 		return nil
 	}
-	// TODO
-	return errors.New("there were errors transpiling")
+	return &TranspileError{Errs: o.errs}
 }
 
 func (t SignalTranspiler) Transpile(input string) (SignalTranspilerOutput, error) {
-	signalInstructions := []*signalInstruction{}
-	for i, line := range strings.Split(input, "\n") {
-		signalInstructions = append(signalInstructions, newSignalInstruction(line, i, false))
+	lines := strings.Split(input, "\n")
+	output := t.transpileLines(lines, 0, t.dialectFor(lines))
+	return output, output.error()
+}
+
+// TranspileAll transpiles a document that may contain several signals, e.g. a pasted channel dump. Signals are
+// separated by a "---" line, or by a MARKET line that appears after the current signal already has one. Each signal
+// gets its own output, with line numbers relative to the whole document.
+func (t SignalTranspiler) TranspileAll(input string) ([]SignalTranspilerOutput, error) {
+	outputs := []SignalTranspilerOutput{}
+	errs := []error{}
+	lines := strings.Split(input, "\n")
+	dialect := t.dialectFor(lines)
+	for _, block := range splitSignalBlocks(lines, dialect) {
+		output := t.transpileLines(block.lines, block.firstLine, dialect)
+		errs = append(errs, output.errs...)
+		outputs = append(outputs, output)
+	}
+	if len(errs) > 0 {
+		return outputs, &TranspileError{Errs: errs}
+	}
+	return outputs, nil
+}
+
+func (t SignalTranspiler) transpileLines(lines []string, firstLine int, dialect *Dialect) SignalTranspilerOutput {
+	if t.extraction {
+		return t.extractLines(lines, firstLine)
 	}
+	return t.transpileInstructions(newSignalInstructions(lines, firstLine, dialect))
+}
+
+func (t SignalTranspiler) transpileInstructions(signalInstructions []*signalInstruction) SignalTranspilerOutput {
 	output := SignalTranspilerOutput{
 		SignalInput: common.SignalCheckInput{ReturnCandlesticks: true},
 		Errors:      []string{},
 		Warnings:    []string{},
+		Diagnostics: []Diagnostic{},
+		Custom:      map[string]interface{}{},
+	}
+	// 0. Apply directives first, because they change how the other instructions are read
+	directives := t.matchers(true)
+	for _, signalInstruction := range signalInstructions {
+		var (
+			err     error
+			matched bool
+		)
+		output, matched, err = signalInstruction.applyFirstMatch(t, output, directives)
+		if matched && err != nil {
+			output.addError(err, signalInstruction)
+		}
 	}
 	// 1. Instructions may be deferred, so do passes until the number of transpiled instructions is 0
 	// 2. Do an inference pass (i.e. apply defaults)
@@ -48,9 +164,12 @@ func (t SignalTranspiler) Transpile(input string) (SignalTranspilerOutput, error
 	// 4. Do a validation pass: if required params are missing, fail. If there are still untranspiled instructions, fail.
 	for _, signalInstruction := range signalInstructions {
 		var err error
-		output, err = signalInstruction.apply(output)
+		output, err = signalInstruction.apply(t, output)
 		if err != nil {
-			output.Errors = append(output.Errors, err.Error())
+			output.addError(err, signalInstruction)
+			if t.isStrict(output) && errors.Is(err, ErrUnrecognizedInstruction) {
+				return t.abortTranspile(output, signalInstructions)
+			}
 			continue
 		}
 	}
@@ -59,36 +178,176 @@ func (t SignalTranspiler) Transpile(input string) (SignalTranspilerOutput, error
 
 	for _, signalInstruction := range signalInstructions {
 		var err error
-		output, err = signalInstruction.apply(output)
+		output, err = signalInstruction.apply(t, output)
 		if err != nil {
-			output.Errors = append(output.Errors, err.Error())
+			output.addError(err, signalInstruction)
 			continue
 		}
 	}
 
+	output.instructions = flattenSegments(signalInstructions)
+	t.resolveInvalidateAt(&output)
+	if output.SignalInput.EnterRangeLow > 0 && output.SignalInput.EnterRangeHigh > 0 {
+		output.resolvePercentages(output.SignalInput.EnterRangeLow, output.SignalInput.EnterRangeHigh)
+	}
 	t.calculateErrorsAndWarnings(&output)
+	// Warnings about a signal that can't possibly work are just noise.
+	if t.validateSemantics(&output) {
+		if len(output.TakeProfitAllocations) > 0 {
+			output.SignalInput.TakeProfitRatios = takeProfitRatios(output.TakeProfitAllocations)
+		}
+		t.calculateWarnings(&output)
+	}
+
+	output.tokenize()
 
+	return output
+}
+
+// abortTranspile stops at the first unrecognized line in strict mode: the lines after it are not applied at all.
+func (t SignalTranspiler) abortTranspile(output SignalTranspilerOutput, signalInstructions []*signalInstruction) SignalTranspilerOutput {
 	for _, signalInstruction := range signalInstructions {
-		if len(signalInstruction.tokenizedInput) == 0 {
-			output.TokenizedInput = append(output.TokenizedInput, []inputToken{{Input: signalInstruction.rawInput, TokenType: TOKEN_ERROR}})
+		if !signalInstruction.isApplied {
+			signalInstruction.tokenizedInput = []InputToken{{Input: signalInstruction.rawInput, TokenType: TOKEN_SKIPPED}}
+			signalInstruction.isApplied = true
+		}
+	}
+	output.instructions = flattenSegments(signalInstructions)
+	output.tokenize()
+	return output
+}
+
+func (t SignalTranspiler) isStrict(sto SignalTranspilerOutput) bool {
+	return t.strict || sto.isStrict
+}
+
+// tokenize makes one line of tokens per input line. Instructions that share a line are separated by what separated
+// them in the line, or by " | " if a dialect rewrote the line.
+func (o *SignalTranspilerOutput) tokenize() {
+	o.TokenizedInput = [][]InputToken{}
+	for i, signalInstruction := range o.instructions {
+		tokens := signalInstruction.tokenizedInput
+		if len(tokens) == 0 {
+			tokens = []InputToken{{Input: signalInstruction.rawInput, TokenType: TOKEN_ERROR}}
+		}
+		if i > 0 && isSameLine(o.instructions[i-1], signalInstruction) {
+			last := len(o.TokenizedInput) - 1
+			separator := signalInstruction.separator
+			if separator == "" {
+				separator = " | "
+			}
+			o.TokenizedInput[last] = append(append(o.TokenizedInput[last], InputToken{Input: separator, TokenType: TOKEN_PUNCTUATION}), tokens...)
 			continue
 		}
-		output.TokenizedInput = append(output.TokenizedInput, signalInstruction.tokenizedInput)
+		o.TokenizedInput = append(o.TokenizedInput, tokens)
 	}
+}
 
-	return output, output.error()
+func isSameLine(a, b *signalInstruction) bool {
+	return !a.isInferred && !b.isInferred && a.lineNumber == b.lineNumber
+}
+
+// isMarketLine is true if the line has a market, which starts a new signal unless the current one has none yet. The
+// market may be followed by other instructions, e.g. "ETH/USDT SHORT".
+func isMarketLine(line string, dialect *Dialect) bool {
+	if isPair(line) {
+		return true
+	}
+	code := strings.Split(line, "//")[0]
+	if starts := segmentStarts(code); len(starts) > 0 && isPair(strings.TrimRight(code[:starts[0]], " \t,;|")) {
+		return true
+	}
+	if dialect == nil || isDialectNeutral(line) {
+		return false
+	}
+	for _, segment := range dialect.Normalize([]string{strings.TrimSpace(strings.Split(line, "//")[0])})[0] {
+		if isPair(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// findInstruction returns the last applied instruction with the given name, or nil if there is none.
+func (o SignalTranspilerOutput) findInstruction(name string) *signalInstruction {
+	if name == "" {
+		return nil
+	}
+	for i := len(o.instructions) - 1; i >= 0; i-- {
+		if o.instructions[i].name == name {
+			return o.instructions[i]
+		}
+	}
+	return nil
+}
+
+type signalBlock struct {
+	lines     []string
+	firstLine int
+}
+
+// splitSignalBlocks splits a document's lines into one block per signal. Blocks with nothing but empty lines and
+// comments are merged into the previous block, so that trailing separators don't produce empty signals.
+func splitSignalBlocks(lines []string, dialect *Dialect) []signalBlock {
+	blocks := []signalBlock{}
+	current := signalBlock{lines: []string{}, firstLine: 0}
+	hasMarket := false
+	flush := func(nextFirstLine int) {
+		if isBlankBlock(current.lines) && len(blocks) > 0 {
+			blocks[len(blocks)-1].lines = append(blocks[len(blocks)-1].lines, current.lines...)
+		} else {
+			blocks = append(blocks, current)
+		}
+		current = signalBlock{lines: []string{}, firstLine: nextFirstLine}
+		hasMarket = false
+	}
+	for i, line := range lines {
+		upLine := strings.ToUpper(line)
+		isMarket := isMarketLine(line, dialect)
+		if isMarket && hasMarket {
+			flush(i)
+		}
+		current.lines = append(current.lines, line)
+		if isMarket {
+			hasMarket = true
+		}
+		if rxSeparator.MatchString(upLine) {
+			flush(i + 1)
+		}
+	}
+	if len(current.lines) > 0 {
+		flush(len(lines))
+	}
+	return blocks
+}
+
+func isBlankBlock(lines []string) bool {
+	for _, line := range lines {
+		if !rxEmpty.MatchString(line) && !rxSeparator.MatchString(line) {
+			return false
+		}
+	}
+	return true
 }
 
 func (t SignalTranspiler) calculateInferredInstructions(sto SignalTranspilerOutput) []*signalInstruction {
 	inferredInstructions := []*signalInstruction{}
+	if t.isStrict(sto) {
+		return inferredInstructions
+	}
 	if sto.SignalInput.Exchange == "" {
-		inferredInstructions = append(inferredInstructions, newSignalInstruction("EXCHANGE: binance", 0, true))
+		exchange := t.defaultExchangeOrDefault()
+		// Leverage only makes sense on a futures exchange
+		if (sto.Leverage != 0 || sto.MarginMode != "") && !isFuturesExchange(exchange) {
+			exchange = common.BINANCE_USDM_FUTURES
+		}
+		inferredInstructions = append(inferredInstructions, newSignalInstruction("EXCHANGE: "+exchange, 0, true))
 	}
 	if !sto.isShortSet {
 		inferredInstructions = append(inferredInstructions, newSignalInstruction("LONG", 0, true))
 	}
-	if sto.SignalInput.InvalidateAfterSeconds == 0 {
-		inferredInstructions = append(inferredInstructions, newSignalInstruction("INVALIDATE AFTER 2 DAYS", 0, true))
+	if sto.SignalInput.InvalidateAfterSeconds == 0 && sto.invalidateAt.IsZero() {
+		inferredInstructions = append(inferredInstructions, newSignalInstruction("INVALIDATE AFTER "+formatTimeout(t.defaultTimeoutOrDefault()), 0, true))
 	}
 	if sto.SignalInput.EnterRangeHigh == 0 && sto.SignalInput.EnterRangeLow == 0 {
 		inferredInstructions = append(inferredInstructions, newSignalInstruction("ENTER: IMMEDIATELY", 0, true))
@@ -98,26 +357,47 @@ func (t SignalTranspiler) calculateInferredInstructions(sto SignalTranspilerOutp
 
 func (t SignalTranspiler) calculateErrorsAndWarnings(sto *SignalTranspilerOutput) {
 	if sto.SignalInput.BaseAsset == "" || sto.SignalInput.QuoteAsset == "" {
-		sto.Errors = append(sto.Errors, fmt.Errorf("%w, e.g. MARKET: BTC/USDT", errMarketRequired).Error())
+		sto.addError(fmt.Errorf("%w, e.g. MARKET: BTC/USDT", ErrMarketRequired), nil)
 	}
 	if sto.SignalInput.InitialISO8601 == "" {
-		sto.Errors = append(sto.Errors, fmt.Errorf("%w, e.g. START AT: 2021-06-22T15:21:03Z", errInitialISO8601Required).Error())
+		sto.addError(fmt.Errorf("%w, e.g. START AT: 2021-06-22T15:21:03Z", ErrInitialISO8601Required), nil)
 	}
 	if sto.SignalInput.EnterRangeLow == 0.0 && sto.SignalInput.EnterRangeHigh == 0.0 {
-		sto.Errors = append(sto.Errors, fmt.Errorf("%w, e.g. ENTER BETWEEN: 0.1 - 0.5 or ENTER: IMMEDIATELY", errEnterRangeRequired).Error())
+		sto.addError(fmt.Errorf("%w, e.g. ENTER BETWEEN: 0.1 - 0.5 or ENTER: IMMEDIATELY", ErrEnterRangeRequired), nil)
+	}
+	// Without inference, the instructions that would otherwise be inferred are required too
+	if !t.isStrict(*sto) {
+		return
+	}
+	if sto.SignalInput.Exchange == "" {
+		sto.addError(fmt.Errorf("%w, e.g. EXCHANGE: BINANCE", ErrExchangeRequired), nil)
+	}
+	if !sto.isShortSet {
+		sto.addError(fmt.Errorf("%w, e.g. LONG or SHORT", ErrDirectionRequired), nil)
+	}
+	if sto.SignalInput.InvalidateAfterSeconds == 0 && sto.invalidateAt.IsZero() {
+		sto.addError(fmt.Errorf("%w, e.g. TIMEOUT AFTER: 2 DAYS", ErrTimeoutRequired), nil)
 	}
 }
 
-type inputToken struct {
+// InputToken is a piece of a line, of one of the TOKEN_* types.
+type InputToken struct {
 	Input     string `json:"input"`
 	TokenType string `json:"tokenType"`
 }
 
 type signalInstruction struct {
 	rawInput       string
+	sourceLine     string
+	separator      string
+	span           []int
+	segments       []*signalInstruction
+	name           string
+	percentages    []float64
 	lineNumber     int
 	err            error
-	tokenizedInput []inputToken
+	warning        error
+	tokenizedInput []InputToken
 	isApplied      bool
 	isInferred     bool
 }
@@ -126,26 +406,54 @@ func newSignalInstruction(rawInput string, lineNumber int, isInferred bool) *sig
 	return &signalInstruction{rawInput: rawInput, lineNumber: lineNumber, isInferred: isInferred}
 }
 
-func (si *signalInstruction) apply(input SignalTranspilerOutput) (SignalTranspilerOutput, error) {
+func (si *signalInstruction) apply(t SignalTranspiler, input SignalTranspilerOutput) (SignalTranspilerOutput, error) {
 	if si.isApplied {
 		return input, nil
 	}
-	for _, instruction := range instructions {
-		resultInstruction, ok := instruction.apply(si.rawInput, &input)
+	output, matched, err := si.applyFirstMatch(t, input, t.matchers(false))
+	if matched && err == nil {
+		return output, nil
+	}
+	// The line may hold several instructions, e.g. "BTC/USDT LONG". If the whole line did match with an error, only
+	// take the pieces if they have none.
+	if segmented, ok := si.applySegments(t, input, matched); ok {
+		return segmented, nil
+	}
+	if matched {
+		return output, err
+	}
+	err = fmt.Errorf("%w at line %v with content [%v]", ErrUnrecognizedInstruction, si.lineNumber, si.rawInput)
+	si.err = err
+	si.isApplied = true
+	return input, err
+}
+
+// applyFirstMatch applies the first of the matchers that matches the instruction, if any.
+func (si *signalInstruction) applyFirstMatch(t SignalTranspiler, input SignalTranspilerOutput, matchers []InstructionMatcher) (SignalTranspilerOutput, bool, error) {
+	if si.isApplied {
+		return input, false, nil
+	}
+	for _, matcher := range matchers {
+		result, ok := matcher.Tokenize(t, si.rawInput, &input)
 		if ok {
-			si.tokenizedInput = resultInstruction.tokenizedInput
+			si.name = matcher.Name
+			si.tokenizedInput = result.Tokens
+			si.percentages = result.percentages
+			if result.Warning != nil {
+				input.addWarning(result.Warning, si)
+			}
+			if comment := trailingComment(si.rawInput); comment != "" && result.Err == nil && si.name != instrNameEmpty {
+				si.tokenizedInput = append(si.tokenizedInput, InputToken{Input: " " + comment, TokenType: TOKEN_COMMENT})
+			}
 			if si.isInferred {
-				si.tokenizedInput = append(si.tokenizedInput, inputToken{Input: " // INFERRED", TokenType: TOKEN_COMMENT})
+				si.tokenizedInput = append(si.tokenizedInput, InputToken{Input: " // INFERRED", TokenType: TOKEN_COMMENT})
 			}
-			si.err = resultInstruction.err
+			si.err = result.Err
 			si.isApplied = true
-			return input, si.err
+			return input, true, si.err
 		}
 	}
-	err := fmt.Errorf("%w at line %v with content [%v]", errUnrecognizedInstruction, si.lineNumber, si.rawInput)
-	si.err = err
-	si.isApplied = true
-	return input, err
+	return input, false, nil
 }
 
 const (
@@ -154,30 +462,48 @@ const (
 	TOKEN_EXPRESSION  = "expression"
 	TOKEN_COMMENT     = "comment"
 	TOKEN_ERROR       = "error"
+	TOKEN_SKIPPED     = "skipped"
 )
 
+// Errors reported by Transpile. They are wrapped with details about the offending input, so use errors.Is on the
+// returned TranspileError to check for them.
 var (
-	errUnrecognizedInstruction            = errors.New("unrecognized instruction")
-	errMarketAlreadySupplied              = errors.New("market already supplied")
-	errEnterRangeAlreadySupplied          = errors.New("enter range already supplied")
-	errInvalidateAfterDaysAlreadySupplied = errors.New("timeout after days already supplied")
-	errIsShortAlreadySupplied             = errors.New("short/long already supplied")
-	errInitialISO8601AlreadySupplied      = errors.New("'start at' already supplied")
-	errExchangeAlreadySupplied            = errors.New("exchange already supplied")
-	errStopLossAlreadySupplied            = errors.New("stop loss already supplied")
-	errMaximumInvalidation7Days           = errors.New("maximum timeout after 7 days")
-	errMalformedInteger                   = errors.New("malformed integer")
-	errMalformedFloat                     = errors.New("malformed float")
-	errInvalidEnterRange                  = errors.New("invalid enter range")
-	errInvalidEnterAt                     = errors.New("invalid 'enter at' format")
-	errUnsupportedExchange                = errors.New("unsupported exchange")
-	errUnsupportedDateTimeFormat          = errors.New("unsupported datetime format")
-	errMarketRequired                     = errors.New("'market' required")
-	errEnterRangeRequired                 = errors.New("enter range required")
-	errInitialISO8601Required             = errors.New("'start at' required")
-	errMixesSeparators                    = errors.New("mixing number separators is not supported, use comma, dash or AND")
+	ErrUnrecognizedInstruction            = errors.New("unrecognized instruction")
+	ErrMarketAlreadySupplied              = errors.New("market already supplied")
+	ErrEnterRangeAlreadySupplied          = errors.New("enter range already supplied")
+	ErrInvalidateAfterDaysAlreadySupplied = errors.New("timeout after days already supplied")
+	ErrIsShortAlreadySupplied             = errors.New("short/long already supplied")
+	ErrInitialISO8601AlreadySupplied      = errors.New("'start at' already supplied")
+	ErrExchangeAlreadySupplied            = errors.New("exchange already supplied")
+	ErrStopLossAlreadySupplied            = errors.New("stop loss already supplied")
+	ErrTrailingStopAlreadySupplied        = errors.New("trailing stop already supplied")
+	ErrBreakEvenAlreadySupplied           = errors.New("break even already supplied")
+	ErrInvalidTrailingStop                = errors.New("invalid trailing stop")
+	ErrLeverageAlreadySupplied            = errors.New("leverage already supplied")
+	ErrMarginModeAlreadySupplied          = errors.New("margin mode already supplied")
+	ErrInvalidLeverage                    = errors.New("invalid leverage")
+	ErrMaximumTimeout                     = errors.New("timeout exceeds the maximum")
+	ErrInvalidTimeout                     = errors.New("invalid timeout")
+	ErrInvalidateBeforeStart              = errors.New("'invalidate at' is not after 'start at'")
+	ErrMalformedInteger                   = errors.New("malformed integer")
+	ErrMalformedFloat                     = errors.New("malformed float")
+	ErrInvalidEnterRange                  = errors.New("invalid enter range")
+	ErrInvalidEnterAt                     = errors.New("invalid 'enter at' format")
+	ErrUnsupportedExchange                = errors.New("unsupported exchange")
+	ErrUnsupportedDateTimeFormat          = errors.New("unsupported datetime format")
+	ErrMarketRequired                     = errors.New("'market' required")
+	ErrEnterRangeRequired                 = errors.New("enter range required")
+	ErrInitialISO8601Required             = errors.New("'start at' required")
+	ErrExchangeRequired                   = errors.New("exchange required in strict mode")
+	ErrDirectionRequired                  = errors.New("direction required in strict mode")
+	ErrTimeoutRequired                    = errors.New("timeout required in strict mode")
+	ErrTimezoneAlreadySupplied            = errors.New("timezone already supplied")
+	ErrUnsupportedTimezone                = errors.New("unsupported timezone")
+	ErrUnsupportedDialect                 = errors.New("unsupported dialect")
+	ErrMixesSeparators                    = errors.New("mixing number separators is not supported, use comma, dash or AND")
 )
 
 type instruction interface {
-	apply(rawInput string, sto *SignalTranspilerOutput) (signalInstruction, bool)
+	name() string
+	apply(t SignalTranspiler, rawInput string, sto *SignalTranspilerOutput) (InstructionResult, bool)
 }
@@ -0,0 +1,164 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitSignalBlocks(t *testing.T) {
+	ts := []struct {
+		name     string
+		input    string
+		expected []signalBlock
+	}{
+		{
+			name:     "one signal",
+			input:    "BTC/USDT\nENTER: 30000\nTP: 32000",
+			expected: []signalBlock{{lines: []string{"BTC/USDT", "ENTER: 30000", "TP: 32000"}, firstLine: 0}},
+		},
+		{
+			name:  "separated by ---",
+			input: "BTC/USDT\nENTER: 30000\n---\nETH/USDT\nENTER: 2000",
+			expected: []signalBlock{
+				{lines: []string{"BTC/USDT", "ENTER: 30000", "---"}, firstLine: 0},
+				{lines: []string{"ETH/USDT", "ENTER: 2000"}, firstLine: 3},
+			},
+		},
+		{
+			name:  "a new market starts a new signal",
+			input: "// channel A\nBTC/USDT\nENTER: 30000\n\nMARKET: ETH/USDT\nENTER: 2000",
+			expected: []signalBlock{
+				{lines: []string{"// channel A", "BTC/USDT", "ENTER: 30000", ""}, firstLine: 0},
+				{lines: []string{"MARKET: ETH/USDT", "ENTER: 2000"}, firstLine: 4},
+			},
+		},
+		{
+			name:  "a market followed by other instructions starts a new signal",
+			input: "BTC/USDT LONG\nENTER: 30000\nETH/USDT, SHORT // second\nENTER: 2000",
+			expected: []signalBlock{
+				{lines: []string{"BTC/USDT LONG", "ENTER: 30000"}, firstLine: 0},
+				{lines: []string{"ETH/USDT, SHORT // second", "ENTER: 2000"}, firstLine: 2},
+			},
+		},
+		{
+			name:     "a pair inside another instruction doesn't start a new signal",
+			input:    "BTC/USDT\nTIMEZONE: Europe/Paris\nENTER: 30000",
+			expected: []signalBlock{{lines: []string{"BTC/USDT", "TIMEZONE: Europe/Paris", "ENTER: 30000"}, firstLine: 0}},
+		},
+		{
+			name:  "market after a separator doesn't start another signal",
+			input: "BTC/USDT\n---\nETH/USDT\nENTER: 2000",
+			expected: []signalBlock{
+				{lines: []string{"BTC/USDT", "---"}, firstLine: 0},
+				{lines: []string{"ETH/USDT", "ENTER: 2000"}, firstLine: 2},
+			},
+		},
+		{
+			name:  "trailing separators and comments go with the last signal",
+			input: "BTC/USDT\nENTER: 30000\n---\n\n// end\n---",
+			expected: []signalBlock{
+				{lines: []string{"BTC/USDT", "ENTER: 30000", "---", "", "// end", "---"}, firstLine: 0},
+			},
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := splitSignalBlocks(strings.Split(tc.input, "\n"), nil); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %+v\ngot      %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTranspileAll(t *testing.T) {
+	input := strings.Join([]string{
+		"BTC/USDT",
+		"ENTER: 30000 - 31000",
+		"START AT: 2021-06-22T15:21:00Z",
+		"---",
+		"ETH/USDT",
+		"ENTER: 2000 - 2100",
+		"FOO",
+		"START AT: 2021-06-22T15:21:00Z",
+		"ADA/USDT SHORT",
+		"ENTER: 1 - 1.1",
+		"START AT: 2021-06-22T15:21:00Z",
+	}, "\n")
+	outputs, err := NewSignalTranspiler().TranspileAll(input)
+	if len(outputs) != 3 {
+		t.Fatalf("expected 3 signals, got %v", len(outputs))
+	}
+	for i, market := range []string{"BTC", "ETH", "ADA"} {
+		if outputs[i].SignalInput.BaseAsset != market {
+			t.Errorf("expected signal %v to be %v, got %v", i, market, outputs[i].SignalInput.BaseAsset)
+		}
+	}
+	if !outputs[2].SignalInput.IsShort {
+		t.Errorf("expected the third signal to be SHORT")
+	}
+	// Only the second signal has an error, and it's on its line in the document
+	if len(outputs[0].Errors) != 0 || len(outputs[2].Errors) != 0 {
+		t.Errorf("expected errors only in the second signal, got %v and %v", outputs[0].Errors, outputs[2].Errors)
+	}
+	if len(outputs[1].Diagnostics) != 1 || outputs[1].Diagnostics[0].Line != 6 {
+		t.Errorf("expected one diagnostic on line 6, got %+v", outputs[1].Diagnostics)
+	}
+	if !errors.Is(err, ErrUnrecognizedInstruction) {
+		t.Errorf("expected the error to wrap %v, got %v", ErrUnrecognizedInstruction, err)
+	}
+}
+
+func TestStrictModeStopsAtUnrecognizedLine(t *testing.T) {
+	ts := []struct {
+		name               string
+		input              string
+		opts               []Option
+		expectedTokenTypes []string
+		expectedErrors     int
+	}{
+		{
+			name:               "option",
+			input:              "BTC/USDT\nSTART AT: 2021-06-22T15:21:00Z\nFOO\nENTER: 30000 - 31000\nBAR",
+			opts:               []Option{WithStrictMode(true)},
+			expectedTokenTypes: []string{TOKEN_INSTRUCTION, TOKEN_INSTRUCTION, TOKEN_ERROR, TOKEN_SKIPPED, TOKEN_SKIPPED},
+			expectedErrors:     1,
+		},
+		{
+			name:               "directive",
+			input:              "STRICT\nBTC/USDT\nSTART AT: 2021-06-22T15:21:00Z\nFOO\nENTER: 30000 - 31000\nBAR",
+			expectedTokenTypes: []string{TOKEN_INSTRUCTION, TOKEN_INSTRUCTION, TOKEN_INSTRUCTION, TOKEN_ERROR, TOKEN_SKIPPED, TOKEN_SKIPPED},
+			expectedErrors:     1,
+		},
+		{
+			name:               "not strict keeps going",
+			input:              "BTC/USDT\nSTART AT: 2021-06-22T15:21:00Z\nFOO\nENTER: 30000 - 31000\nBAR",
+			expectedTokenTypes: []string{TOKEN_INSTRUCTION, TOKEN_INSTRUCTION, TOKEN_ERROR, TOKEN_INSTRUCTION, TOKEN_ERROR},
+			expectedErrors:     2,
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := NewSignalTranspiler(tc.opts...).Transpile(tc.input)
+			if !errors.Is(err, ErrUnrecognizedInstruction) {
+				t.Fatalf("expected error %v, got %v", ErrUnrecognizedInstruction, err)
+			}
+			if len(output.Errors) != tc.expectedErrors {
+				t.Errorf("expected %v errors, got %v", tc.expectedErrors, output.Errors)
+			}
+			// Inferred instructions come after the input's lines
+			lines := strings.Count(tc.input, "\n") + 1
+			if len(output.TokenizedInput) < lines {
+				t.Fatalf("expected at least %v lines of tokens, got %v", lines, output.TokenizedInput)
+			}
+			actual := []string{}
+			for _, line := range output.TokenizedInput[:lines] {
+				actual = append(actual, line[0].TokenType)
+			}
+			if !reflect.DeepEqual(actual, tc.expectedTokenTypes) {
+				t.Errorf("expected lines of %v, got %v", tc.expectedTokenTypes, actual)
+			}
+		})
+	}
+}
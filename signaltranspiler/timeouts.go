@@ -0,0 +1,86 @@
+package signaltranspiler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var rxTimeoutPart = regexp.MustCompile(`(\d+)\s*(WEEKS|WEEK|W|DAYS|DAY|D|HOURS|HOUR|HRS|HR|H|MINUTES|MINUTE|MINS|MIN|M)`)
+
+var timeoutUnits = map[string]time.Duration{
+	"W": 7 * 24 * time.Hour,
+	"D": 24 * time.Hour,
+	"H": time.Hour,
+	"M": time.Minute,
+}
+
+// parseTimeout parses durations like "3 DAYS", "90 MINUTES" or "1D12H". s must be uppercase. Each unit may only
+// appear once, and the timeout must be longer than zero.
+func parseTimeout(s string) (time.Duration, error) {
+	var (
+		timeout time.Duration
+		seen    = map[string]bool{}
+	)
+	for _, part := range rxTimeoutPart.FindAllStringSubmatch(s, -1) {
+		n, err := strconv.Atoi(part[1])
+		if err != nil {
+			return 0, fmt.Errorf("%w [%v]", ErrMalformedInteger, part[1])
+		}
+		unit := part[2][:1]
+		if seen[unit] {
+			return 0, fmt.Errorf("%w: %v appears more than once [%v]", ErrInvalidTimeout, part[2], strings.TrimSpace(s))
+		}
+		seen[unit] = true
+		timeout += time.Duration(n) * timeoutUnits[unit]
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("%w: it must be longer than zero [%v]", ErrInvalidTimeout, strings.TrimSpace(s))
+	}
+	return timeout, nil
+}
+
+// formatTimeout renders a timeout in days, hours and minutes, e.g. "1 DAY 12 HOURS". Seconds are dropped.
+func formatTimeout(timeout time.Duration) string {
+	parts := []string{}
+	for _, unit := range []struct {
+		name     string
+		duration time.Duration
+	}{{"DAY", 24 * time.Hour}, {"HOUR", time.Hour}, {"MINUTE", time.Minute}} {
+		n := int(timeout / unit.duration)
+		timeout -= time.Duration(n) * unit.duration
+		switch {
+		case n == 1:
+			parts = append(parts, "1 "+unit.name)
+		case n > 1:
+			parts = append(parts, fmt.Sprintf("%v %vS", n, unit.name))
+		}
+	}
+	if len(parts) == 0 {
+		return "0 MINUTES"
+	}
+	return strings.Join(parts, " ")
+}
+
+// resolveInvalidateAt turns an INVALIDATE AT date into a timeout, now that the start date is known.
+func (t SignalTranspiler) resolveInvalidateAt(sto *SignalTranspilerOutput) {
+	if sto.invalidateAt.IsZero() || sto.SignalInput.InitialISO8601 == "" {
+		return
+	}
+	si := sto.findInstruction(instrNameTimeout)
+	startAt, err := time.Parse(time.RFC3339, string(sto.SignalInput.InitialISO8601))
+	if err != nil {
+		return
+	}
+	timeout := sto.invalidateAt.Sub(startAt)
+	switch {
+	case timeout <= 0:
+		sto.addError(fmt.Errorf("%w [%v]", ErrInvalidateBeforeStart, sto.invalidateAt.UTC().Format(time.RFC3339)), si)
+	case timeout > t.maxTimeoutOrDefault():
+		sto.addError(fmt.Errorf("%w of %v [%v]", ErrMaximumTimeout, formatTimeout(t.maxTimeoutOrDefault()), formatTimeout(timeout)), si)
+	default:
+		sto.SignalInput.InvalidateAfterSeconds = int(timeout / time.Second)
+	}
+}
@@ -0,0 +1,71 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeouts(t *testing.T) {
+	ts := []struct {
+		name            string
+		timeout         string
+		expectedSeconds int
+		expectedErr     error
+	}{
+		{name: "days", timeout: "TIMEOUT AFTER: 2 DAYS", expectedSeconds: 2 * 86400},
+		{name: "weeks", timeout: "TIMEOUT AFTER 3 WEEKS", expectedSeconds: 21 * 86400},
+		{name: "minutes", timeout: "INVALIDATE IN 90 MINUTES", expectedSeconds: 90 * 60},
+		{name: "compact", timeout: "TIMEOUT AFTER 1D12H", expectedSeconds: 36 * 3600},
+		{name: "several units", timeout: "TIMEOUT AFTER 1 DAY 6 HOURS 30 MINUTES", expectedSeconds: 86400 + 6*3600 + 30*60},
+		{name: "absolute date", timeout: "INVALIDATE AT: 2021-06-24T15:21:00Z", expectedSeconds: 2 * 86400},
+		{name: "inferred", timeout: "", expectedSeconds: int(DefaultTimeout.Seconds())},
+		{name: "repeated unit", timeout: "TIMEOUT AFTER 2 DAYS 2 DAYS", expectedErr: ErrInvalidTimeout},
+		{name: "repeated unit written differently", timeout: "TIMEOUT AFTER 1H 30M 2HRS", expectedErr: ErrInvalidTimeout},
+		{name: "zero", timeout: "TIMEOUT AFTER 0 DAYS", expectedErr: ErrInvalidTimeout},
+		{name: "over the maximum", timeout: "TIMEOUT AFTER 5 WEEKS", expectedErr: ErrMaximumTimeout},
+		{name: "absolute date over the maximum", timeout: "INVALIDATE AT: 2021-08-22T15:21:00Z", expectedErr: ErrMaximumTimeout},
+		{name: "absolute date before the start", timeout: "INVALIDATE AT: 2021-06-21T15:21:00Z", expectedErr: ErrInvalidateBeforeStart},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := NewSignalTranspiler().Transpile("BTC/USDT\nSTART AT: 2021-06-22T15:21:00Z\nENTER: 30000 - 31000\n" + tc.timeout)
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.SignalInput.InvalidateAfterSeconds != tc.expectedSeconds {
+				t.Errorf("expected %v seconds, got %v", tc.expectedSeconds, output.SignalInput.InvalidateAfterSeconds)
+			}
+		})
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	ts := []struct {
+		name            string
+		timeout         time.Duration
+		expectedSeconds int
+	}{
+		{name: "whole minutes", timeout: 90 * time.Minute, expectedSeconds: 90 * 60},
+		{name: "under a minute rounds up", timeout: 30 * time.Second, expectedSeconds: 60},
+		{name: "seconds round up", timeout: 2*time.Minute + time.Second, expectedSeconds: 3 * 60},
+		{name: "zero keeps the default", timeout: 0, expectedSeconds: int(DefaultTimeout / time.Second)},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := NewSignalTranspiler(WithDefaultTimeout(tc.timeout)).Transpile("BTC/USDT\nENTER: 30000 - 31000\nSTART AT: 2021-06-22T15:21:00Z")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.SignalInput.InvalidateAfterSeconds != tc.expectedSeconds {
+				t.Errorf("expected %v seconds, got %v", tc.expectedSeconds, output.SignalInput.InvalidateAfterSeconds)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var (
+	ErrTakeProfitBehindEntry      = errors.New("take profit is not beyond the enter range")
+	ErrStopLossWrongSide          = errors.New("stop loss is on the wrong side of the enter range")
+	ErrStopLossBeyondTakeProfit   = errors.New("stop loss is beyond a take profit")
+	ErrTakeProfitUnallocated      = errors.New("take profit has no allocation")
+	ErrTakeProfitAllocationSum    = errors.New("take profit allocations don't add up to 100%")
+	ErrBreakEvenAfterNoTakeProfit = errors.New("break even is after a take profit that doesn't exist")
+	ErrLeverageRequiresFutures    = errors.New("leverage and margin mode require a futures exchange")
+)
+
+// semanticRules check fields against each other. Unlike warning rules, they catch signals that can't possibly work,
+// so they report errors, and the signal fails before any time is spent checking it against exchange data. Like
+// warning rules, they return the name of the instruction the error is about. They get the whole output, because some
+// fields, like break even, aren't part of the signal checker's input.
+var semanticRules = []func(sto SignalTranspilerOutput) (string, error){
+	checkTakeProfitBehindEntry,
+	checkStopLossWrongSide,
+	checkStopLossBeyondTakeProfit,
+	checkTakeProfitAllocations,
+	checkBreakEven,
+	checkFuturesExchange,
+}
+
+// validateSemantics reports whether the signal passed every semantic rule.
+func (t SignalTranspiler) validateSemantics(sto *SignalTranspilerOutput) bool {
+	ok := true
+	for _, rule := range semanticRules {
+		instructionName, err := rule(*sto)
+		if err != nil {
+			sto.addError(err, sto.findInstruction(instructionName))
+			ok = false
+		}
+	}
+	return ok
+}
+
+// checkBreakEven also counts the percentage take profits that are only resolved once the entry price is known.
+func checkBreakEven(sto SignalTranspilerOutput) (string, error) {
+	takeProfits := len(sto.SignalInput.TakeProfits)
+	if sto.pendingPercentages {
+		takeProfits += len(sto.TakeProfitPercentages)
+	}
+	if sto.BreakEvenAfterTakeProfit > takeProfits {
+		return instrNameBreakEven, fmt.Errorf("%w: TP%v, but there are %v take profits", ErrBreakEvenAfterNoTakeProfit, sto.BreakEvenAfterTakeProfit, takeProfits)
+	}
+	return "", nil
+}
+
+func checkFuturesExchange(sto SignalTranspilerOutput) (string, error) {
+	exchange := sto.SignalInput.Exchange
+	if exchange == "" || isFuturesExchange(exchange) {
+		return "", nil
+	}
+	if sto.Leverage != 0 {
+		return instrNameLeverage, fmt.Errorf("%w, but the exchange is %v", ErrLeverageRequiresFutures, strings.ToUpper(exchange))
+	}
+	if sto.MarginMode != "" {
+		return instrNameMarginMode, fmt.Errorf("%w, but the exchange is %v", ErrLeverageRequiresFutures, strings.ToUpper(exchange))
+	}
+	return "", nil
+}
+
+func isFuturesExchange(exchange string) bool {
+	return isSInSS(strings.ToUpper(exchange), futuresExchangeList)
+}
+
+func checkTakeProfitBehindEntry(sto SignalTranspilerOutput) (string, error) {
+	in := sto.SignalInput
+	if !hasEnterRange(in) {
+		return "", nil
+	}
+	for _, tp := range in.TakeProfits {
+		if !in.IsShort && tp <= in.EnterRangeHigh {
+			return instrNameTakeProfit, fmt.Errorf("%w: %v is not above %v on a LONG", ErrTakeProfitBehindEntry, renderFloat(tp), renderFloat(in.EnterRangeHigh))
+		}
+		if in.IsShort && tp >= in.EnterRangeLow {
+			return instrNameTakeProfit, fmt.Errorf("%w: %v is not below %v on a SHORT", ErrTakeProfitBehindEntry, renderFloat(tp), renderFloat(in.EnterRangeLow))
+		}
+	}
+	return "", nil
+}
+
+func checkStopLossWrongSide(sto SignalTranspilerOutput) (string, error) {
+	in := sto.SignalInput
+	if !hasEnterRange(in) || !hasStopLoss(in) {
+		return "", nil
+	}
+	if !in.IsShort && in.StopLoss >= in.EnterRangeLow {
+		return instrNameStopLoss, fmt.Errorf("%w: %v is not below %v on a LONG", ErrStopLossWrongSide, renderFloat(in.StopLoss), renderFloat(in.EnterRangeLow))
+	}
+	if in.IsShort && in.StopLoss <= in.EnterRangeHigh {
+		return instrNameStopLoss, fmt.Errorf("%w: %v is not above %v on a SHORT", ErrStopLossWrongSide, renderFloat(in.StopLoss), renderFloat(in.EnterRangeHigh))
+	}
+	return "", nil
+}
+
+// checkStopLossBeyondTakeProfit also covers signals that enter immediately, where there is no enter range to compare to.
+func checkStopLossBeyondTakeProfit(sto SignalTranspilerOutput) (string, error) {
+	in := sto.SignalInput
+	if !hasStopLoss(in) {
+		return "", nil
+	}
+	for _, tp := range in.TakeProfits {
+		if !in.IsShort && in.StopLoss >= tp {
+			return instrNameStopLoss, fmt.Errorf("%w: %v is not below take profit %v on a LONG", ErrStopLossBeyondTakeProfit, renderFloat(in.StopLoss), renderFloat(tp))
+		}
+		if in.IsShort && in.StopLoss <= tp {
+			return instrNameStopLoss, fmt.Errorf("%w: %v is not above take profit %v on a SHORT", ErrStopLossBeyondTakeProfit, renderFloat(in.StopLoss), renderFloat(tp))
+		}
+	}
+	return "", nil
+}
+
+// checkTakeProfitAllocations requires that, if any take profit has an allocation, all of them do, and that they add up
+// to the whole position.
+func checkTakeProfitAllocations(sto SignalTranspilerOutput) (string, error) {
+	var (
+		in          = sto.SignalInput
+		allocations = sto.TakeProfitAllocations
+	)
+	if len(allocations) == 0 {
+		return "", nil
+	}
+	sum := 0.0
+	for i, allocation := range allocations {
+		if allocation <= 0 && i < len(in.TakeProfits) {
+			return instrNameTakeProfit, fmt.Errorf("%w: %v", ErrTakeProfitUnallocated, renderFloat(in.TakeProfits[i]))
+		}
+		sum += float64(allocation)
+	}
+	if len(allocations) < len(in.TakeProfits) {
+		return instrNameTakeProfit, fmt.Errorf("%w: %v", ErrTakeProfitUnallocated, renderFloat(in.TakeProfits[len(allocations)]))
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		return instrNameTakeProfit, fmt.Errorf("%w: they add up to %v%%", ErrTakeProfitAllocationSum, renderPercentage(sum))
+	}
+	return "", nil
+}
@@ -0,0 +1,184 @@
+package signaltranspiler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/marianogappa/signal-checker/common"
+)
+
+// Warning rule names, to switch rules on and off with DisableWarningRule and EnableWarningRule.
+const (
+	WARNING_UNSORTED_TAKE_PROFITS = "unsorted_take_profits"
+	WARNING_EXTREME_RISK_REWARD   = "extreme_risk_reward"
+	WARNING_FUTURE_START          = "future_start"
+	WARNING_FAR_STOP_LOSS         = "far_stop_loss"
+
+	WARNING_STOP_LOSS_BEYOND_LIQUIDATION = "stop_loss_beyond_liquidation"
+	WARNING_UNLISTED_MARKET              = "unlisted_market"
+)
+
+const (
+	minRiskRewardRatio = 0.2
+	maxRiskRewardRatio = 10.0
+	farStopLossRatio   = 0.25
+)
+
+var (
+	ErrUnsortedTakeProfits = errors.New("take profits are not sorted")
+	ErrExtremeRiskReward   = errors.New("extreme risk/reward ratio")
+	ErrFutureStart         = errors.New("'start at' is in the future")
+	ErrFarStopLoss         = errors.New("stop loss is very far from entry")
+
+	ErrStopLossBeyondLiquidation = errors.New("stop loss is beyond the liquidation price")
+	ErrUnlistedMarket            = errors.New("market not listed on the exchange")
+)
+
+// warningRule flags a signal that is valid but suspicious. check returns the warning, plus the name of the
+// instruction it is about ("" for the signal as a whole), or a nil error if the signal looks fine. If strictError is
+// set, the warning is an error in strict mode, which takes nothing on trust.
+type warningRule struct {
+	name        string
+	check       func(t SignalTranspiler, sto SignalTranspilerOutput) (string, error)
+	strictError bool
+}
+
+var warningRules = []warningRule{
+	{WARNING_UNSORTED_TAKE_PROFITS, checkUnsortedTakeProfits, false},
+	{WARNING_EXTREME_RISK_REWARD, checkExtremeRiskReward, false},
+	{WARNING_FUTURE_START, checkFutureStart, false},
+	{WARNING_FAR_STOP_LOSS, checkFarStopLoss, false},
+	{WARNING_STOP_LOSS_BEYOND_LIQUIDATION, checkStopLossBeyondLiquidation, false},
+	{WARNING_UNLISTED_MARKET, checkUnlistedMarket, true},
+}
+
+// DisableWarningRule stops the warning rule with the given name (e.g. WARNING_FAR_STOP_LOSS) from running, including
+// in strict mode, where WARNING_UNLISTED_MARKET is an error.
+func (t *SignalTranspiler) DisableWarningRule(name string) {
+	if t.disabledWarningRules == nil {
+		t.disabledWarningRules = map[string]bool{}
+	}
+	t.disabledWarningRules[name] = true
+}
+
+// EnableWarningRule undoes DisableWarningRule.
+func (t *SignalTranspiler) EnableWarningRule(name string) {
+	delete(t.disabledWarningRules, name)
+}
+
+func (t SignalTranspiler) calculateWarnings(sto *SignalTranspilerOutput) {
+	for _, rule := range warningRules {
+		if t.disabledWarningRules[rule.name] {
+			continue
+		}
+		instructionName, err := rule.check(t, *sto)
+		switch {
+		case err == nil:
+		case rule.strictError && t.isStrict(*sto):
+			sto.addError(err, sto.findInstruction(instructionName))
+		default:
+			sto.addWarning(err, sto.findInstruction(instructionName))
+		}
+	}
+}
+
+// LiquidationPrice estimates the price at which a position entered at entry with the given leverage loses its whole
+// margin. It leaves out the maintenance margin and fees, so the exchange liquidates a bit earlier.
+func LiquidationPrice(entry, leverage float64, isShort bool) float64 {
+	if isShort {
+		return entry * (1 + 1/leverage)
+	}
+	return entry * (1 - 1/leverage)
+}
+
+// checkStopLossBeyondLiquidation assumes the worst entry in the enter range, i.e. the one liquidated soonest.
+func checkStopLossBeyondLiquidation(t SignalTranspiler, sto SignalTranspilerOutput) (string, error) {
+	in := sto.SignalInput
+	if sto.Leverage == 0 {
+		return "", nil
+	}
+	if !hasEnterRange(in) || !hasStopLoss(in) {
+		// With ENTER: IMMEDIATELY a percentage stop loss is all there is to go by
+		if distance := float64(sto.StopLossPercentage) / 100; sto.pendingPercentages && distance >= 1/float64(sto.Leverage) {
+			return instrNameStopLoss, fmt.Errorf("%w: %v%% away from entry, but %vX is liquidated %.2f%% away", ErrStopLossBeyondLiquidation, sto.StopLossPercentage, sto.Leverage, 100/float64(sto.Leverage))
+		}
+		return "", nil
+	}
+	entry := float64(in.EnterRangeHigh)
+	if in.IsShort {
+		entry = float64(in.EnterRangeLow)
+	}
+	liquidation := LiquidationPrice(entry, float64(sto.Leverage), in.IsShort)
+	stopLoss := float64(in.StopLoss)
+	if (!in.IsShort && stopLoss <= liquidation) || (in.IsShort && stopLoss >= liquidation) {
+		return instrNameStopLoss, fmt.Errorf("%w of %v at %vX", ErrStopLossBeyondLiquidation, renderFloat(common.JsonFloat64(liquidation)), sto.Leverage)
+	}
+	return "", nil
+}
+
+func hasEnterRange(in common.SignalCheckInput) bool {
+	return in.EnterRangeLow > 0 && in.EnterRangeHigh > 0
+}
+
+func hasStopLoss(in common.SignalCheckInput) bool {
+	return in.StopLoss > 0
+}
+
+func enterRangeMiddle(in common.SignalCheckInput) float64 {
+	return float64(in.EnterRangeLow+in.EnterRangeHigh) / 2
+}
+
+func checkUnsortedTakeProfits(t SignalTranspiler, sto SignalTranspilerOutput) (string, error) {
+	in := sto.SignalInput
+	for i := 1; i < len(in.TakeProfits); i++ {
+		if !in.IsShort && in.TakeProfits[i] <= in.TakeProfits[i-1] {
+			return instrNameTakeProfit, fmt.Errorf("%w, a LONG's should go up: %v", ErrUnsortedTakeProfits, renderFloats(in.TakeProfits))
+		}
+		if in.IsShort && in.TakeProfits[i] >= in.TakeProfits[i-1] {
+			return instrNameTakeProfit, fmt.Errorf("%w, a SHORT's should go down: %v", ErrUnsortedTakeProfits, renderFloats(in.TakeProfits))
+		}
+	}
+	return "", nil
+}
+
+func checkExtremeRiskReward(t SignalTranspiler, sto SignalTranspilerOutput) (string, error) {
+	in := sto.SignalInput
+	if !hasEnterRange(in) || !hasStopLoss(in) || len(in.TakeProfits) == 0 {
+		return "", nil
+	}
+	entry := enterRangeMiddle(in)
+	risk := math.Abs(entry - float64(in.StopLoss))
+	reward := math.Abs(float64(in.TakeProfits[0]) - entry)
+	if risk == 0 {
+		return "", nil
+	}
+	if ratio := reward / risk; ratio < minRiskRewardRatio || ratio > maxRiskRewardRatio {
+		return instrNameTakeProfit, fmt.Errorf("%w of %.2f for the first take profit", ErrExtremeRiskReward, ratio)
+	}
+	return "", nil
+}
+
+func checkFutureStart(t SignalTranspiler, sto SignalTranspilerOutput) (string, error) {
+	in := sto.SignalInput
+	initial, err := in.InitialISO8601.Time()
+	if err != nil {
+		return "", nil
+	}
+	if initial.After(t.currentTime()) {
+		return instrNameStartAt, fmt.Errorf("%w: %v", ErrFutureStart, in.InitialISO8601)
+	}
+	return "", nil
+}
+
+func checkFarStopLoss(t SignalTranspiler, sto SignalTranspilerOutput) (string, error) {
+	in := sto.SignalInput
+	if !hasEnterRange(in) || !hasStopLoss(in) {
+		return "", nil
+	}
+	entry := enterRangeMiddle(in)
+	if distance := math.Abs(entry-float64(in.StopLoss)) / entry; distance > farStopLossRatio {
+		return instrNameStopLoss, fmt.Errorf("%w: %.0f%% away", ErrFarStopLoss, distance*100)
+	}
+	return "", nil
+}